@@ -0,0 +1,58 @@
+// Command sqshdump prints the superblock summary and directory listing of a squashfs image, using
+// the squashfs package to do the actual parsing.
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+
+	"github.com/diskfs/squashfs-util/squashfs"
+)
+
+func main() {
+	args := os.Args[1:]
+	if len(args) != 1 {
+		log.Fatalf("Usage: %s <filename>", os.Args[0])
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		log.Fatalf("Error opening file %s: %v", args[0], err)
+	}
+	defer f.Close()
+
+	r, err := squashfs.NewReader(f)
+	if err != nil {
+		log.Fatalf("Error reading squashfs image %s: %v", args[0], err)
+	}
+
+	info := r.Info()
+	fmt.Printf("compression %d\n", info.Compression)
+	fmt.Printf("version %s\n", info.Version)
+	fmt.Printf("mod time %v\n", info.ModTime)
+	fmt.Printf("blocksize %d\n", info.BlockSize)
+	fmt.Printf("filesystem size %d\n", info.Size)
+	fmt.Printf("inodes %d\n", info.Inodes)
+	fmt.Printf("fragment count %d\n", info.FragmentCount)
+	fmt.Println()
+
+	if err := fs.WalkDir(r, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		uid, gid := "-", "-"
+		if sys, ok := info.Sys().(*squashfs.SysInfo); ok {
+			uid, gid = fmt.Sprint(sys.Uid), fmt.Sprint(sys.Gid)
+		}
+		fmt.Printf("%v %4s/%-4s %10d %s\n", info.Mode(), uid, gid, info.Size(), p)
+		return nil
+	}); err != nil {
+		log.Fatalf("Error walking squashfs image %s: %v", args[0], err)
+	}
+}
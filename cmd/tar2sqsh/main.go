@@ -0,0 +1,50 @@
+// Command tar2sqsh reads a tar stream from stdin and writes a squashfs 4.0 image, using the
+// squashfs package to do the actual encoding.
+package main
+
+import (
+	"archive/tar"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/diskfs/squashfs-util/squashfs"
+)
+
+func main() {
+	compression := flag.Uint("compression", 1, "compression id to use (1=gzip, 4=xz, 5=lz4, 6=zstd)")
+	blockSize := flag.Uint("block-size", 131072, "data block size in bytes")
+	noFragments := flag.Bool("no-fragments", false, "disable fragment packing")
+	dedup := flag.Bool("dedup", false, "deduplicate identical file content")
+	exportable := flag.Bool("exportable", false, "build an export table")
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) != 1 {
+		log.Fatalf("Usage: %s [flags] <output file>", os.Args[0])
+	}
+
+	out, err := os.Create(args[0])
+	if err != nil {
+		log.Fatalf("Error creating file %s: %v", args[0], err)
+	}
+	defer out.Close()
+
+	w, err := squashfs.NewWriter(out, squashfs.WriterOptions{
+		BlockSize:   uint32(*blockSize),
+		Compression: uint16(*compression),
+		NoFragments: *noFragments,
+		Dedup:       *dedup,
+		Exportable:  *exportable,
+	})
+	if err != nil {
+		log.Fatalf("Error creating squashfs writer: %v", err)
+	}
+
+	if err := w.WriteTar(tar.NewReader(os.Stdin)); err != nil {
+		log.Fatalf("Error writing tar stream to %s: %v", args[0], err)
+	}
+	if err := w.Close(); err != nil {
+		log.Fatalf("Error finishing squashfs image %s: %v", args[0], err)
+	}
+}
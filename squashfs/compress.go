@@ -0,0 +1,364 @@
+package squashfs
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/ulikunitz/xz"
+)
+
+// compression ids as stored in the superblock's "compression" field
+const (
+	compressionGzip uint16 = 1
+	compressionLZMA uint16 = 2
+	compressionLZO  uint16 = 3
+	compressionXZ   uint16 = 4
+	compressionLZ4  uint16 = 5
+	compressionZstd uint16 = 6
+)
+
+// Decompressor decompresses a single metadata or data block. src holds the raw
+// on-disk bytes for the block; dst, if it has enough capacity, may be reused
+// to avoid an allocation per block, mirroring the dst/src convention used by
+// the standard library's flate and zlib packages.
+type Decompressor interface {
+	Decompress(dst, src []byte) ([]byte, error)
+}
+
+// decompressors is the registry of known Decompressor implementations, keyed by
+// the squashfs compressor id found in the superblock. RegisterDecompressor adds
+// to or overrides entries in this registry.
+var decompressors = map[uint16]Decompressor{
+	compressionGzip: &gzipDecompressor{},
+	compressionXZ:   &xzDecompressor{},
+	compressionLZ4:  &lz4Decompressor{},
+	compressionZstd: &zstdDecompressor{},
+}
+
+// RegisterDecompressor registers d as the Decompressor to use for the given
+// squashfs compressor id, replacing any built-in implementation. This lets
+// callers plug in alternate or additional compressors, e.g. lzma or lzo,
+// which are not implemented here.
+func RegisterDecompressor(id uint16, d Decompressor) {
+	decompressors[id] = d
+}
+
+// getDecompressor returns the Decompressor registered for the given
+// compressor id, or an error if none is registered.
+func getDecompressor(id uint16) (Decompressor, error) {
+	d, ok := decompressors[id]
+	if !ok {
+		return nil, fmt.Errorf("no decompressor registered for compression id %d", id)
+	}
+	return d, nil
+}
+
+// newDecompressorInstance returns a Decompressor for id that is safe for one Reader's exclusive
+// use. The built-in decompressors carry per-image compressor-options state (e.g. an xz dictionary
+// cap, set by parseCompressorOptions) in a mutable field, so each Reader gets its own zero-valued
+// copy rather than the shared registry singleton - otherwise two Readers open at once, or two
+// opened one after another with different options blocks, would race on or leak each other's
+// tuning parameters. A Decompressor added via RegisterDecompressor is assumed to be free of such
+// state and is returned as registered.
+func newDecompressorInstance(id uint16) (Decompressor, error) {
+	d, err := getDecompressor(id)
+	if err != nil {
+		return nil, err
+	}
+	switch d.(type) {
+	case *gzipDecompressor:
+		return &gzipDecompressor{}, nil
+	case *xzDecompressor:
+		return &xzDecompressor{}, nil
+	case *lz4Decompressor:
+		return &lz4Decompressor{}, nil
+	case *zstdDecompressor:
+		return &zstdDecompressor{}, nil
+	default:
+		return d, nil
+	}
+}
+
+// Compressor compresses a single metadata or data block for the Writer, the mirror image of
+// Decompressor. src holds the raw, uncompressed block; dst, if it has enough capacity, may be
+// reused to avoid an allocation per block.
+type Compressor interface {
+	Compress(dst, src []byte) ([]byte, error)
+}
+
+// compressors is the registry of known Compressor implementations, keyed by the squashfs
+// compressor id that will be stored in the superblock. RegisterCompressor adds to or overrides
+// entries in this registry.
+var compressors = map[uint16]Compressor{
+	compressionGzip: &gzipCompressor{},
+	compressionXZ:   &xzCompressor{},
+	compressionLZ4:  &lz4Compressor{},
+	compressionZstd: &zstdCompressor{},
+}
+
+// RegisterCompressor registers c as the Compressor to use for the given squashfs compressor id,
+// replacing any built-in implementation.
+func RegisterCompressor(id uint16, c Compressor) {
+	compressors[id] = c
+}
+
+// getCompressor returns the Compressor registered for the given compressor id, or an error if none
+// is registered.
+func getCompressor(id uint16) (Compressor, error) {
+	c, ok := compressors[id]
+	if !ok {
+		return nil, fmt.Errorf("no compressor registered for compression id %d", id)
+	}
+	return c, nil
+}
+
+// gzipCompressor compresses to the same raw zlib (RFC 1950) stream gzipDecompressor expects.
+type gzipCompressor struct{}
+
+func (g *gzipCompressor) Compress(dst, src []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst[:0])
+	zw := zlib.NewWriter(buf)
+	if _, err := zw.Write(src); err != nil {
+		return nil, fmt.Errorf("error compressing gzip block: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("error closing zlib writer: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// xzCompressor compresses the squashfs "xz" compressor.
+type xzCompressor struct{}
+
+func (x *xzCompressor) Compress(dst, src []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst[:0])
+	xw, err := xz.NewWriter(buf)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create xz writer: %v", err)
+	}
+	if _, err := xw.Write(src); err != nil {
+		return nil, fmt.Errorf("error compressing xz block: %v", err)
+	}
+	if err := xw.Close(); err != nil {
+		return nil, fmt.Errorf("error closing xz writer: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// lz4Compressor compresses the squashfs "lz4" compressor.
+type lz4Compressor struct{}
+
+func (l *lz4Compressor) Compress(dst, src []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst[:0])
+	lw := lz4.NewWriter(buf)
+	if _, err := lw.Write(src); err != nil {
+		return nil, fmt.Errorf("error compressing lz4 block: %v", err)
+	}
+	if err := lw.Close(); err != nil {
+		return nil, fmt.Errorf("error closing lz4 writer: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// zstdCompressor compresses the squashfs "zstd" compressor using klauspost/compress/zstd.
+type zstdCompressor struct{}
+
+func (z *zstdCompressor) Compress(dst, src []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst[:0])
+	zw, err := zstd.NewWriter(buf)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create zstd writer: %v", err)
+	}
+	if _, err := zw.Write(src); err != nil {
+		return nil, fmt.Errorf("error compressing zstd block: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("error closing zstd writer: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// gzipOptions holds the tuning parameters parsed from the gzip compressor
+// options metadata block, per the squashfs on-disk format.
+type gzipOptions struct {
+	compressionLevel uint32
+	windowSize       uint16
+	strategies       uint16
+}
+
+// gzipDecompressor decompresses the squashfs "gzip" compressor. Despite the
+// name, squashfs stores this as a raw zlib (RFC 1950) stream rather than a
+// gzip (RFC 1952) stream, so it is read with compress/zlib.
+// options is kept for parity with the other decompressors and for callers that inspect it, but
+// compress/zlib has no knob for it: the zlib stream's own header already encodes window size, and
+// compressionLevel/strategies only ever steer the encoder.
+type gzipDecompressor struct {
+	options *gzipOptions
+}
+
+func (g *gzipDecompressor) Decompress(dst, src []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create zlib reader: %v", err)
+	}
+	defer r.Close()
+	buf := bytes.NewBuffer(dst[:0])
+	if _, err := io.Copy(buf, r); err != nil {
+		return nil, fmt.Errorf("error decompressing gzip block: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// xzOptions holds the tuning parameters parsed from the xz compressor
+// options metadata block.
+type xzOptions struct {
+	dictionarySize uint32
+	filters        uint32
+}
+
+// xzMinDictCap mirrors lzma.MinDictCap: the smallest dictionary capacity ulikunitz/xz accepts.
+// dictionarySize values below this (which should not occur on a well-formed image) fall back to
+// the reader's own default instead of failing to construct.
+const xzMinDictCap = 1 << 12
+
+// xzDecompressor decompresses the squashfs "xz" compressor.
+type xzDecompressor struct {
+	options *xzOptions
+}
+
+func (x *xzDecompressor) Decompress(dst, src []byte) ([]byte, error) {
+	var r *xz.Reader
+	var err error
+	if x.options != nil && x.options.dictionarySize >= xzMinDictCap {
+		cfg := xz.ReaderConfig{DictCap: int(x.options.dictionarySize)}
+		r, err = cfg.NewReader(bytes.NewReader(src))
+	} else {
+		r, err = xz.NewReader(bytes.NewReader(src))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to create xz reader: %v", err)
+	}
+	buf := bytes.NewBuffer(dst[:0])
+	if _, err := io.Copy(buf, r); err != nil {
+		return nil, fmt.Errorf("error decompressing xz block: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// lz4Options holds the tuning parameters parsed from the lz4 compressor
+// options metadata block.
+type lz4Options struct {
+	version uint32
+	flags   uint32
+}
+
+// lz4Decompressor decompresses the squashfs "lz4" compressor. options is kept for parity and
+// inspection; the LZ4 frame itself is self-describing, and pierrec/lz4's reader has no setting
+// that version/flags (e.g. the high-compression flag, which only affects the encoder) would change.
+type lz4Decompressor struct {
+	options *lz4Options
+}
+
+func (l *lz4Decompressor) Decompress(dst, src []byte) ([]byte, error) {
+	r := lz4.NewReader(bytes.NewReader(src))
+	buf := bytes.NewBuffer(dst[:0])
+	if _, err := io.Copy(buf, r); err != nil {
+		return nil, fmt.Errorf("error decompressing lz4 block: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// zstdOptions holds the tuning parameters parsed from the zstd compressor
+// options metadata block.
+type zstdOptions struct {
+	level uint32
+}
+
+// zstdDecompressor decompresses the squashfs "zstd" compressor using
+// klauspost/compress/zstd. options is kept for parity and inspection; level is an encoder-only
+// parameter and the zstd frame header already carries what the decoder needs.
+type zstdDecompressor struct {
+	options *zstdOptions
+}
+
+func (z *zstdDecompressor) Decompress(dst, src []byte) ([]byte, error) {
+	d, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create zstd reader: %v", err)
+	}
+	defer d.Close()
+	out, err := d.DecodeAll(src, dst[:0])
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing zstd block: %v", err)
+	}
+	return out, nil
+}
+
+// parseCompressorOptions reads the compressor-options metadata block that immediately follows the
+// superblock when the compressorOptions flag is set, and feeds the parsed tuning parameters into
+// d - the calling Reader's own decompressor instance - if it knows what to do with them. d must be
+// a per-Reader instance (see newDecompressorInstance), not a shared registry entry, since the
+// options it records (e.g. an xz dictionary cap) are specific to this one image.
+func parseCompressorOptions(r io.ReaderAt, compression uint16, d Decompressor) (interface{}, error) {
+	_, b, err := readMetadataBlock(r, superblockSize, d)
+	if err != nil {
+		return nil, fmt.Errorf("could not read compressor options block: %v", err)
+	}
+	switch compression {
+	case compressionGzip:
+		if len(b) < 8 {
+			return nil, fmt.Errorf("gzip compressor options block was %d bytes, expected at least 8", len(b))
+		}
+		opts := &gzipOptions{
+			compressionLevel: binary.LittleEndian.Uint32(b[0:4]),
+			windowSize:       binary.LittleEndian.Uint16(b[4:6]),
+			strategies:       binary.LittleEndian.Uint16(b[6:8]),
+		}
+		if gd, ok := d.(*gzipDecompressor); ok {
+			gd.options = opts
+		}
+		return opts, nil
+	case compressionXZ:
+		if len(b) < 8 {
+			return nil, fmt.Errorf("xz compressor options block was %d bytes, expected at least 8", len(b))
+		}
+		opts := &xzOptions{
+			dictionarySize: binary.LittleEndian.Uint32(b[0:4]),
+			filters:        binary.LittleEndian.Uint32(b[4:8]),
+		}
+		if xd, ok := d.(*xzDecompressor); ok {
+			xd.options = opts
+		}
+		return opts, nil
+	case compressionLZ4:
+		if len(b) < 8 {
+			return nil, fmt.Errorf("lz4 compressor options block was %d bytes, expected at least 8", len(b))
+		}
+		opts := &lz4Options{
+			version: binary.LittleEndian.Uint32(b[0:4]),
+			flags:   binary.LittleEndian.Uint32(b[4:8]),
+		}
+		if ld, ok := d.(*lz4Decompressor); ok {
+			ld.options = opts
+		}
+		return opts, nil
+	case compressionZstd:
+		if len(b) < 4 {
+			return nil, fmt.Errorf("zstd compressor options block was %d bytes, expected at least 4", len(b))
+		}
+		opts := &zstdOptions{
+			level: binary.LittleEndian.Uint32(b[0:4]),
+		}
+		if zd, ok := d.(*zstdDecompressor); ok {
+			zd.options = opts
+		}
+		return opts, nil
+	default:
+		return nil, fmt.Errorf("no known compressor options format for compression id %d", compression)
+	}
+}
@@ -0,0 +1,42 @@
+package squashfs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ulikunitz/xz"
+)
+
+// TestXzDecompressorUsesDictCap verifies that an xz decompressor carrying a parsed
+// compressor-options dictionary size still decompresses correctly, and that a dictionary size
+// below the minimum falls back to the reader's default instead of failing to construct.
+func TestXzDecompressorUsesDictCap(t *testing.T) {
+	want := []byte("some data to round-trip through xz with a configured dictionary size")
+
+	var compressed bytes.Buffer
+	xw, err := xz.NewWriter(&compressed)
+	if err != nil {
+		t.Fatalf("xz.NewWriter: %v", err)
+	}
+	if _, err := xw.Write(want); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := xw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	d := &xzDecompressor{options: &xzOptions{dictionarySize: 1 << 20}}
+	got, err := d.Decompress(nil, compressed.Bytes())
+	if err != nil {
+		t.Fatalf("Decompress: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Fatalf("Decompress() = %q, want %q", got, want)
+	}
+
+	// A below-minimum dictionary size must fall back to the reader's default instead of failing.
+	d = &xzDecompressor{options: &xzOptions{dictionarySize: 1}}
+	if _, err := d.Decompress(nil, compressed.Bytes()); err != nil {
+		t.Fatalf("Decompress with tiny dictionarySize: %v", err)
+	}
+}
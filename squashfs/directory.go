@@ -0,0 +1,110 @@
+package squashfs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"path"
+)
+
+// directoryHeader is the 12-byte header that precedes each run of directory entries sharing the
+// same start block and parent inode number.
+type directoryHeader struct {
+	count      uint32
+	startBlock uint32
+	inode      uint32
+}
+
+// directoryEntryRaw is a single parsed directory entry, prior to being resolved into a tree entry.
+type directoryEntryRaw struct {
+	offset      uint16
+	inodeNumber uint16
+	inodeType   inodeType
+	name        string
+	startBlock  uint32
+}
+
+// parseDirectoryHeader parses the header of a directory metadata run.
+func parseDirectoryHeader(b []byte) (*directoryHeader, error) {
+	if len(b) < dirHeaderSize {
+		return nil, fmt.Errorf("header was %d bytes, less than minimum %d", len(b), dirHeaderSize)
+	}
+	return &directoryHeader{
+		count:      binary.LittleEndian.Uint32(b[0:4]) + 1,
+		startBlock: binary.LittleEndian.Uint32(b[4:8]),
+		inode:      binary.LittleEndian.Uint32(b[8:12]),
+	}, nil
+}
+
+// parseDirectoryEntry parses a single raw directory entry, returning it along with the number of
+// bytes consumed.
+func parseDirectoryEntry(b []byte) (*directoryEntryRaw, int, error) {
+	// ensure we have enough bytes to parse
+	if len(b) < dirEntryMinSize {
+		return nil, 0, fmt.Errorf("directory entry was %d bytes, less than minimum %d", len(b), dirEntryMinSize)
+	}
+
+	offset := binary.LittleEndian.Uint16(b[0:2])
+	inode := binary.LittleEndian.Uint16(b[2:4])
+	entryType := inodeType(binary.LittleEndian.Uint16(b[4:6]))
+	nameSize := binary.LittleEndian.Uint16(b[6:8])
+	realNameSize := nameSize + 1
+
+	// make sure name is legitimate size
+	if nameSize > dirNameMaxSize {
+		return nil, 0, fmt.Errorf("name size was %d bytes, greater than maximum %d", nameSize, dirNameMaxSize)
+	}
+	if int(realNameSize+dirEntryMinSize) > len(b) {
+		return nil, 0, fmt.Errorf("dir entry plus size of name is %d, larger than available bytes %d", nameSize+dirEntryMinSize, len(b))
+	}
+
+	// read in the name
+	name := string(b[8 : 8+realNameSize])
+	return &directoryEntryRaw{
+		offset:      offset,
+		inodeNumber: inode,
+		name:        name,
+		inodeType:   entryType,
+	}, int(8 + realNameSize), nil
+}
+
+// parseDirectory parses the entire contents of a directory metadata run into child tree entries of
+// the directory at p.
+func parseDirectory(p string, b []byte) ([]*entry, error) {
+	var entries []*entry
+	for pos := 0; pos+dirHeaderSize < len(b); {
+		hdr, err := parseDirectoryHeader(b[pos:])
+		if err != nil {
+			return nil, fmt.Errorf("could not parse directory header: %v", err)
+		}
+		if hdr.count > maxDirEntries {
+			return nil, fmt.Errorf("corrupted directory, had %d entries instead of max %d", hdr.count, maxDirEntries)
+		}
+		pos += dirHeaderSize
+		for count := uint32(0); count < hdr.count; count++ {
+			raw, size, err := parseDirectoryEntry(b[pos:])
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse entry at position %d: %v", pos, err)
+			}
+			raw.startBlock = hdr.startBlock
+			entries = append(entries, &entry{
+				name:      raw.name,
+				path:      fsJoin(p, raw.name),
+				block:     raw.startBlock,
+				offset:    raw.offset,
+				inodeType: raw.inodeType,
+			})
+			// increment the position
+			pos += size
+		}
+	}
+	return entries, nil
+}
+
+// fsJoin joins a directory path and a child name using io/fs path conventions, where the root
+// directory is named "." and every other path is slash-separated without a leading slash.
+func fsJoin(dir, name string) string {
+	if dir == "." || dir == "" {
+		return name
+	}
+	return path.Join(dir, name)
+}
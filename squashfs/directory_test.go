@@ -0,0 +1,58 @@
+package squashfs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+)
+
+// dirHeaderBytes builds a raw directoryHeader, encoding count as count-1 as the on-disk format
+// requires.
+func dirHeaderBytes(count, startBlock, inode uint32) []byte {
+	b := make([]byte, dirHeaderSize)
+	binary.LittleEndian.PutUint32(b[0:4], count-1)
+	binary.LittleEndian.PutUint32(b[4:8], startBlock)
+	binary.LittleEndian.PutUint32(b[8:12], inode)
+	return b
+}
+
+// dirEntryBytes builds a single raw directory entry with a name of the given length.
+func dirEntryBytes(offset, inode uint16, t inodeType, name string) []byte {
+	b := make([]byte, dirEntryMinSize+len(name))
+	binary.LittleEndian.PutUint16(b[0:2], offset)
+	binary.LittleEndian.PutUint16(b[2:4], inode)
+	binary.LittleEndian.PutUint16(b[4:6], uint16(t))
+	binary.LittleEndian.PutUint16(b[6:8], uint16(len(name)-1))
+	copy(b[8:], name)
+	return b
+}
+
+// TestParseDirectoryAtMaxEntries verifies that a directory run with exactly maxDirEntries entries
+// is accepted. parseDirectoryHeader already decodes the on-disk count-1 field back into a count of
+// maxDirEntries, so parseDirectory must not add another +1 on top when comparing against the
+// limit, or every maximally-sized directory run would be rejected as corrupt.
+func TestParseDirectoryAtMaxEntries(t *testing.T) {
+	var b []byte
+	b = append(b, dirHeaderBytes(maxDirEntries, 0, 1)...)
+	for i := 0; i < maxDirEntries; i++ {
+		b = append(b, dirEntryBytes(uint16(i), uint16(i), inodeBasicFile, fmt.Sprintf("f%d", i))...)
+	}
+
+	entries, err := parseDirectory(".", b)
+	if err != nil {
+		t.Fatalf("parseDirectory() error = %v, want nil", err)
+	}
+	if len(entries) != maxDirEntries {
+		t.Fatalf("got %d entries, want %d", len(entries), maxDirEntries)
+	}
+}
+
+// TestParseDirectoryOverMaxEntries verifies that a directory run with more than maxDirEntries
+// entries is still rejected as corrupt.
+func TestParseDirectoryOverMaxEntries(t *testing.T) {
+	b := append(dirHeaderBytes(maxDirEntries+1, 0, 1), 0)
+
+	if _, err := parseDirectory(".", b); err == nil {
+		t.Fatalf("parseDirectory() error = nil, want error for %d entries", maxDirEntries+1)
+	}
+}
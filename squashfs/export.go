@@ -0,0 +1,65 @@
+package squashfs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// exportEntriesPerBlock is the number of packed uint64 inode refs that fit in a single metadata block.
+const exportEntriesPerBlock = metadataSize / 8
+
+// readExportTable reads the export table: an indirect table of metadata-block pointers rooted at
+// exportTableStart, each block holding packed uint64 inode references indexed by inode number - 1.
+// It lets NFS-style lookup-by-inode-number work without a linear scan of the directory tree.
+func readExportTable(r io.ReaderAt, exportTableStart uint64, inodeCount uint32, d Decompressor) ([]uint64, error) {
+	if exportTableStart == noTableSentinel || inodeCount == 0 {
+		return nil, nil
+	}
+	numBlocks := int((int(inodeCount) + exportEntriesPerBlock - 1) / exportEntriesPerBlock)
+	ptrBytes := make([]byte, numBlocks*8)
+	if err := readFullAt(r, ptrBytes, int64(exportTableStart)); err != nil {
+		return nil, fmt.Errorf("could not read export table index: %v", err)
+	}
+
+	refs := make([]uint64, 0, inodeCount)
+	for i := 0; i < numBlocks; i++ {
+		blockStart := binary.LittleEndian.Uint64(ptrBytes[i*8 : i*8+8])
+		_, b, err := readMetadataBlock(r, int64(blockStart), d)
+		if err != nil {
+			return nil, fmt.Errorf("could not read export table block %d at %d: %v", i, blockStart, err)
+		}
+		for off := 0; off+8 <= len(b) && len(refs) < int(inodeCount); off += 8 {
+			refs = append(refs, binary.LittleEndian.Uint64(b[off:off+8]))
+		}
+	}
+	return refs, nil
+}
+
+// inodeLoc is the (metadata block, offset) pair that uniquely locates an inode within the inode
+// table, used to resolve an export-table reference back to the entry discovered while walking the
+// directory tree.
+type inodeLoc struct {
+	block  uint32
+	offset uint16
+}
+
+// LookupInode resolves a squashfs inode number to the tree entry it refers to, via the image's
+// export table. It returns an error if the image was not built with the exportable flag, or if n
+// is out of range.
+func (r *Reader) LookupInode(n uint32) (fs.FileInfo, error) {
+	if r.exportTable == nil {
+		return nil, fmt.Errorf("image does not have an export table")
+	}
+	if n == 0 || int(n) > len(r.exportTable) {
+		return nil, fmt.Errorf("inode number %d out of range of %d entries", n, len(r.exportTable))
+	}
+	ref := r.exportTable[n-1]
+	block, offset := parseInodeRef(ref)
+	e, ok := r.byInodeLoc[inodeLoc{block, offset}]
+	if !ok {
+		return nil, fmt.Errorf("no entry found for inode %d at block %d offset %d", n, block, offset)
+	}
+	return fileInfo{e: e, r: r}, nil
+}
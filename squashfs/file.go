@@ -0,0 +1,114 @@
+package squashfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"time"
+)
+
+// fileInfo adapts an entry to fs.FileInfo. r is used to resolve the entry's uid/gid/xattr indexes
+// to their actual values on demand.
+type fileInfo struct {
+	e *entry
+	r *Reader
+}
+
+func (fi fileInfo) Name() string {
+	if fi.e.path == "." {
+		return "."
+	}
+	return path.Base(fi.e.path)
+}
+func (fi fileInfo) Size() int64 {
+	if fi.e.file == nil {
+		return 0
+	}
+	return int64(fi.e.file.fileSize)
+}
+func (fi fileInfo) Mode() fs.FileMode { return fi.e.permMode() }
+func (fi fileInfo) IsDir() bool       { return fi.e.isDir() }
+func (fi fileInfo) ModTime() time.Time {
+	if fi.e.header == nil {
+		return time.Time{}
+	}
+	return fi.e.header.modTime
+}
+
+// Sys returns a *SysInfo carrying the real UID/GID and extended attributes of the entry, resolved
+// through the reader's id and xattr tables. Extended attributes are resolved for regular files
+// only; directories and other inode types always report none.
+func (fi fileInfo) Sys() interface{} {
+	info := &SysInfo{}
+	if fi.e.header != nil {
+		if uid, err := fi.r.idFor(fi.e.header.uidIdx); err == nil {
+			info.Uid = uid
+		}
+		if gid, err := fi.r.idFor(fi.e.header.gidIdx); err == nil {
+			info.Gid = gid
+		}
+	}
+	if fi.e.file != nil && fi.e.file.xattrIdx != noXattr {
+		if xattrs, err := fi.r.xattrsFor(fi.e.file.xattrIdx); err == nil {
+			info.Xattrs = xattrs
+		}
+	}
+	return info
+}
+
+// SysInfo is returned by a squashfs fs.FileInfo's Sys method, surfacing metadata that fs.FileInfo
+// itself has no room for.
+type SysInfo struct {
+	Uid    uint32
+	Gid    uint32
+	Xattrs map[string][]byte
+}
+
+// dirEntry adapts an entry to fs.DirEntry.
+type dirEntry struct {
+	e *entry
+	r *Reader
+}
+
+func (d dirEntry) Name() string               { return fileInfo{e: d.e, r: d.r}.Name() }
+func (d dirEntry) IsDir() bool                { return d.e.isDir() }
+func (d dirEntry) Type() fs.FileMode          { return fileInfo{e: d.e, r: d.r}.Mode().Type() }
+func (d dirEntry) Info() (fs.FileInfo, error) { return fileInfo{e: d.e, r: d.r}, nil }
+
+// dirFile implements fs.ReadDirFile for a directory entry.
+type dirFile struct {
+	entry  *entry
+	r      *Reader
+	offset int
+}
+
+func (d *dirFile) Stat() (fs.FileInfo, error) { return fileInfo{e: d.entry, r: d.r}, nil }
+func (d *dirFile) Close() error               { return nil }
+func (d *dirFile) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.entry.path, Err: errors.New("is a directory")}
+}
+
+func (d *dirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	children := d.entry.children[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entry.children)
+		entries := make([]fs.DirEntry, len(children))
+		for i, c := range children {
+			entries[i] = dirEntry{e: c, r: d.r}
+		}
+		return entries, nil
+	}
+	if len(children) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(children) {
+		n = len(children)
+	}
+	entries := make([]fs.DirEntry, n)
+	for i, c := range children[:n] {
+		entries[i] = dirEntry{e: c, r: d.r}
+	}
+	d.offset += n
+	return entries, nil
+}
@@ -0,0 +1,118 @@
+package squashfs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	basicFileFixedSize    = 16
+	extendedFileFixedSize = 40
+	// noFragment marks a file that keeps its tail in the block list instead of a fragment.
+	noFragment = 0xffffffff
+	// noXattr marks an inode that has no associated xattr descriptor.
+	noXattr = 0xffffffff
+	// blockSizeUncompressed is bit 24 of a block-list/fragment-table size field.
+	blockSizeUncompressed = 0x1000000
+	blockSizeMask         = 0xffffff
+)
+
+// fileMeta describes how to locate and reassemble a regular file's content: a run of full blocks
+// starting at blockStart, sized by blockSizes, followed by an optional fragment tail.
+type fileMeta struct {
+	blockStart uint64
+	fileSize   uint64
+	fragIndex  uint32
+	fragOffset uint32
+	blockSizes []uint32
+	// blockOffsets[i] is the on-disk offset, relative to blockStart, of block i. Precomputed once
+	// here so random-access reads don't need to re-sum the block-size list on every call.
+	blockOffsets []int64
+	// xattrIdx indexes the xattr table's descriptor list, or is noXattr if the inode carries none.
+	// Basic (non-extended) file inodes never carry one.
+	xattrIdx uint32
+}
+
+// parseFileInode reads a basic or extended file inode body, located immediately after the common
+// inode header at (block, offset) within the inode table starting at inodeTableStart, including its
+// trailing block-size list.
+func parseFileInode(r io.ReaderAt, inodeTableStart int64, block uint32, offset uint16, t inodeType, blockSize uint32, d Decompressor) (*fileMeta, error) {
+	var (
+		blockStart uint64
+		fileSize   uint64
+		fragIndex  uint32
+		fragOffset uint32
+		xattrIdx   uint32 = noXattr
+		listBlock  uint32
+		listOffset uint16
+	)
+	switch t {
+	case inodeBasicFile:
+		b, nextBlock, nextOffset, err := readMetadataAt(r, inodeTableStart, block, offset, basicFileFixedSize, d)
+		if err != nil {
+			return nil, err
+		}
+		blockStart = uint64(binary.LittleEndian.Uint32(b[0:4]))
+		fragIndex = binary.LittleEndian.Uint32(b[4:8])
+		fragOffset = binary.LittleEndian.Uint32(b[8:12])
+		fileSize = uint64(binary.LittleEndian.Uint32(b[12:16]))
+		listBlock, listOffset = nextBlock, nextOffset
+	case inodeExtendedFile:
+		b, nextBlock, nextOffset, err := readMetadataAt(r, inodeTableStart, block, offset, extendedFileFixedSize, d)
+		if err != nil {
+			return nil, err
+		}
+		blockStart = binary.LittleEndian.Uint64(b[0:8])
+		fileSize = binary.LittleEndian.Uint64(b[8:16])
+		// b[16:24] is the sparse byte count, b[24:28] is nlink - neither needed here.
+		fragIndex = binary.LittleEndian.Uint32(b[28:32])
+		fragOffset = binary.LittleEndian.Uint32(b[32:36])
+		xattrIdx = binary.LittleEndian.Uint32(b[36:40])
+		listBlock, listOffset = nextBlock, nextOffset
+	default:
+		return nil, fmt.Errorf("inode type %d is not a file inode", t)
+	}
+
+	blockCount := int(fileSize / uint64(blockSize))
+	if fragIndex == noFragment && fileSize%uint64(blockSize) != 0 {
+		blockCount++
+	}
+
+	blockSizes := make([]uint32, blockCount)
+	blockOffsets := make([]int64, blockCount)
+	if blockCount > 0 {
+		raw, _, _, err := readMetadataAt(r, inodeTableStart, listBlock, listOffset, blockCount*4, d)
+		if err != nil {
+			return nil, err
+		}
+		var offset int64
+		for i := 0; i < blockCount; i++ {
+			size := binary.LittleEndian.Uint32(raw[i*4 : i*4+4])
+			blockSizes[i] = size
+			blockOffsets[i] = offset
+			offset += int64(size & blockSizeMask)
+		}
+	}
+
+	return &fileMeta{
+		blockStart:   blockStart,
+		fileSize:     fileSize,
+		fragIndex:    fragIndex,
+		fragOffset:   fragOffset,
+		blockSizes:   blockSizes,
+		blockOffsets: blockOffsets,
+		xattrIdx:     xattrIdx,
+	}, nil
+}
+
+func readFullAt(r io.ReaderAt, b []byte, offset int64) error {
+	n, err := r.ReadAt(b, offset)
+	if err != nil {
+		return fmt.Errorf("could not read %d bytes at %d: %v", len(b), offset, err)
+	}
+	if n != len(b) {
+		return fmt.Errorf("read %d instead of expected %d bytes at %d", n, len(b), offset)
+	}
+	return nil
+}
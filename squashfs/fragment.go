@@ -0,0 +1,49 @@
+package squashfs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// fragmentEntrySize is the on-disk size of a single fragment table entry: start (uint64), size
+// (uint32) and an unused uint32.
+const fragmentEntrySize = 16
+
+// fragmentEntriesPerBlock is the number of fragment entries packed into a single metadata block.
+const fragmentEntriesPerBlock = metadataSize / fragmentEntrySize
+
+// fragmentEntry locates one fragment block on disk.
+type fragmentEntry struct {
+	start uint64
+	size  uint32
+}
+
+// readFragmentTable reads the fragment table: an on-disk array of metadata-block pointers rooted
+// at fragTableStart, each pointing to a metadata block packed with fragmentEntry records.
+func readFragmentTable(r io.ReaderAt, fragTableStart uint64, fragCount uint32, d Decompressor) ([]fragmentEntry, error) {
+	if fragCount == 0 {
+		return nil, nil
+	}
+	numBlocks := int((fragCount + fragmentEntriesPerBlock - 1) / fragmentEntriesPerBlock)
+	ptrBytes := make([]byte, numBlocks*8)
+	if err := readFullAt(r, ptrBytes, int64(fragTableStart)); err != nil {
+		return nil, fmt.Errorf("could not read fragment table index: %v", err)
+	}
+
+	entries := make([]fragmentEntry, 0, fragCount)
+	for i := 0; i < numBlocks; i++ {
+		blockStart := binary.LittleEndian.Uint64(ptrBytes[i*8 : i*8+8])
+		_, b, err := readMetadataBlock(r, int64(blockStart), d)
+		if err != nil {
+			return nil, fmt.Errorf("could not read fragment table block %d at %d: %v", i, blockStart, err)
+		}
+		for off := 0; off+fragmentEntrySize <= len(b) && len(entries) < int(fragCount); off += fragmentEntrySize {
+			entries = append(entries, fragmentEntry{
+				start: binary.LittleEndian.Uint64(b[off : off+8]),
+				size:  binary.LittleEndian.Uint32(b[off+8 : off+12]),
+			})
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,46 @@
+package squashfs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// idEntriesPerBlock is the number of packed uint32 ids that fit in a single metadata block.
+const idEntriesPerBlock = metadataSize / 4
+
+// readIDTable reads the id table: an indirect table of metadata-block pointers rooted at
+// idTableStart, each block holding packed uint32 UIDs/GIDs indexed by an inode's uidIdx/gidIdx.
+func readIDTable(r io.ReaderAt, idTableStart uint64, idCount uint16, d Decompressor) ([]uint32, error) {
+	if idCount == 0 || idTableStart == noTableSentinel {
+		return nil, nil
+	}
+	numBlocks := int((int(idCount) + idEntriesPerBlock - 1) / idEntriesPerBlock)
+	ptrBytes := make([]byte, numBlocks*8)
+	if err := readFullAt(r, ptrBytes, int64(idTableStart)); err != nil {
+		return nil, fmt.Errorf("could not read id table index: %v", err)
+	}
+
+	ids := make([]uint32, 0, idCount)
+	for i := 0; i < numBlocks; i++ {
+		blockStart := binary.LittleEndian.Uint64(ptrBytes[i*8 : i*8+8])
+		_, b, err := readMetadataBlock(r, int64(blockStart), d)
+		if err != nil {
+			return nil, fmt.Errorf("could not read id table block %d at %d: %v", i, blockStart, err)
+		}
+		for off := 0; off+4 <= len(b) && len(ids) < int(idCount); off += 4 {
+			ids = append(ids, binary.LittleEndian.Uint32(b[off:off+4]))
+		}
+	}
+	return ids, nil
+}
+
+// idFor resolves an id-table index (as found in an inode header's uidIdx/gidIdx) to the UID/GID it
+// represents. An out-of-range index is reported rather than silently returning 0, since 0 is itself
+// a valid UID/GID (root).
+func (r *Reader) idFor(idx uint16) (uint32, error) {
+	if int(idx) >= len(r.idTable) {
+		return 0, fmt.Errorf("id index %d out of range of %d id table entries", idx, len(r.idTable))
+	}
+	return r.idTable[idx], nil
+}
@@ -0,0 +1,96 @@
+package squashfs
+
+import (
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+// TestReaderIDAndExportTables builds an image with Exportable set and opens it, which reads back
+// its id table (WriteFS records every entry as owned by uid/gid 0) and its export table, then
+// verifies that every inode number in the export table resolves to a tree entry via LookupInode
+// and that out-of-range inode numbers are rejected.
+func TestReaderIDAndExportTables(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a": &fstest.MapFile{Data: []byte("a"), Mode: 0644},
+		"b": &fstest.MapFile{Data: []byte("b"), Mode: 0644},
+		"c": &fstest.MapFile{Data: []byte("c"), Mode: 0644},
+	}
+
+	f, err := os.CreateTemp(t.TempDir(), "squashfs-idexport-*.sqsh")
+	if err != nil {
+		t.Fatalf("could not create temp file: %v", err)
+	}
+	defer f.Close()
+
+	w, err := NewWriter(f, WriterOptions{Exportable: true})
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if err := w.WriteFS(fsys); err != nil {
+		t.Fatalf("WriteFS: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r, err := NewReader(f)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	if r.exportTable == nil {
+		t.Fatalf("exportTable is nil, want a populated export table since Exportable was set")
+	}
+
+	entries, err := r.ReadDir(".")
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != len(fsys) {
+		t.Fatalf("got %d directory entries, want %d", len(entries), len(fsys))
+	}
+
+	for n := uint32(1); n <= uint32(len(r.exportTable)); n++ {
+		if _, err := r.LookupInode(n); err != nil {
+			t.Fatalf("LookupInode(%d): %v", n, err)
+		}
+	}
+
+	if _, err := r.LookupInode(0); err == nil {
+		t.Fatalf("LookupInode(0) error = nil, want error for out-of-range inode number")
+	}
+	if _, err := r.LookupInode(uint32(len(r.exportTable)) + 1); err == nil {
+		t.Fatalf("LookupInode() with an out-of-range inode number error = nil, want error")
+	}
+}
+
+// TestReaderNoExportTable verifies that LookupInode reports a clear error, rather than a nil
+// pointer panic, on an image written without WriterOptions.Exportable.
+func TestReaderNoExportTable(t *testing.T) {
+	fsys := fstest.MapFS{"a": &fstest.MapFile{Data: []byte("a"), Mode: 0644}}
+
+	f, err := os.CreateTemp(t.TempDir(), "squashfs-noexport-*.sqsh")
+	if err != nil {
+		t.Fatalf("could not create temp file: %v", err)
+	}
+	defer f.Close()
+
+	w, err := NewWriter(f, WriterOptions{})
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if err := w.WriteFS(fsys); err != nil {
+		t.Fatalf("WriteFS: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r, err := NewReader(f)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	if _, err := r.LookupInode(1); err == nil {
+		t.Fatalf("LookupInode() error = nil, want error for an image with no export table")
+	}
+}
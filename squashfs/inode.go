@@ -0,0 +1,60 @@
+package squashfs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// inodeHeader is the 16-byte header common to every inode type.
+type inodeHeader struct {
+	inodeType inodeType
+	uidIdx    uint16
+	gidIdx    uint16
+	modTime   time.Time
+	index     uint32
+	mode      os.FileMode
+}
+
+// readInodeHeader reads the common inode header located at (block, offset) within the inode table
+// starting at inodeTableStart, returning it along with the (block, offset) position immediately
+// following it so the caller can chain a read of the type-specific body that follows.
+func readInodeHeader(r io.ReaderAt, inodeTableStart int64, block uint32, offset uint16, d Decompressor) (*inodeHeader, uint32, uint16, error) {
+	b, nextBlock, nextOffset, err := readMetadataAt(r, inodeTableStart, block, offset, inodeHeaderSize, d)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("error reading inode header at block %d offset %d: %v", block, offset, err)
+	}
+	return &inodeHeader{
+		inodeType: inodeType(binary.LittleEndian.Uint16(b[0:2])),
+		mode:      os.FileMode(binary.LittleEndian.Uint16(b[2:4])),
+		uidIdx:    binary.LittleEndian.Uint16(b[4:6]),
+		gidIdx:    binary.LittleEndian.Uint16(b[6:8]),
+		modTime:   time.Unix(int64(binary.LittleEndian.Uint32(b[8:12])), 0),
+		index:     binary.LittleEndian.Uint32(b[12:16]),
+	}, nextBlock, nextOffset, nil
+}
+
+// parseDirectoryInode extracts the directory-table pointer (block index, size and offset), plus the
+// parent directory's inode number, from the body of a basic or extended directory inode. Both
+// layouts place parent_inode at the same 4-byte offset.
+func parseDirectoryInode(b []byte, t inodeType) (uint32, uint16, uint16, uint32) {
+	var (
+		dirBlockIndex uint32
+		dirSize       uint16
+		offset        uint16
+	)
+	switch t {
+	case inodeBasicDirectory:
+		dirBlockIndex = binary.LittleEndian.Uint32(b[0:4])
+		dirSize = binary.LittleEndian.Uint16(b[8:10])
+		offset = binary.LittleEndian.Uint16(b[10:12])
+	case inodeExtendedDirectory:
+		dirBlockIndex = binary.LittleEndian.Uint32(b[8:12])
+		dirSize = binary.LittleEndian.Uint16(b[4:8])
+		offset = binary.LittleEndian.Uint16(b[18:20])
+	}
+	parentInode := binary.LittleEndian.Uint32(b[12:16])
+	return dirBlockIndex, dirSize, offset, parentInode
+}
@@ -0,0 +1,114 @@
+package squashfs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// readMetadataBlock reads a single metadata block at location, decompressing it with d if
+// necessary. It returns the number of on-disk bytes consumed (including the 2-byte header) along
+// with the (decompressed, if applicable) block contents. d is a Reader's own decompressor
+// instance rather than a shared registry lookup, since some decompressors carry per-image
+// compressor-options state (e.g. an xz dictionary cap) that must not be shared between images.
+func readMetadataBlock(r io.ReaderAt, location int64, d Decompressor) (int, []byte, error) {
+	// read the size and compression
+	b := make([]byte, 2)
+	n, err := r.ReadAt(b, location)
+	if err != nil {
+		return 0, nil, fmt.Errorf("could not read size bytes for metadata block at %d: %v", location, err)
+	}
+	if n != len(b) {
+		return 0, nil, fmt.Errorf("read %d instead of expected %d bytes for metadata block at location %d", n, len(b), location)
+	}
+	header := binary.LittleEndian.Uint16(b[:2])
+	size := header & 0x7fff
+	compressed := header&0x8000 != 0x8000
+	b = make([]byte, size)
+	n, err = r.ReadAt(b, location+2)
+	if err != nil {
+		return 0, nil, fmt.Errorf("could not data size bytes for metadata block at %d: %v", location, err)
+	}
+	if n != len(b) {
+		return 0, nil, fmt.Errorf("read %d instead of expected %d bytes for metadata block at location %d", n, len(b), location)
+	}
+	if !compressed {
+		return len(b) + 2, b, nil
+	}
+	out, err := d.Decompress(nil, b)
+	if err != nil {
+		return 0, nil, fmt.Errorf("could not decompress metadata block at %d: %v", location, err)
+	}
+	return len(b) + 2, out, nil
+}
+
+// readMetadata read as many bytes of metadata as required for the given size, with the byteOffset provided as a starting
+// point into the first block. Can read multiple blocks if necessary, e.g. if a block is 8192 bytes (standard), and
+// requests to read 500 bytes beginning at offset 8000 into the first block. d decompresses any block that is not
+// stored raw.
+func readMetadata(r io.ReaderAt, firstBlock int64, initialBlockOffset uint32, byteOffset uint16, size int, d Decompressor) ([]byte, error) {
+	b, _, _, err := readMetadataAt(r, firstBlock, initialBlockOffset, byteOffset, size, d)
+	return b, err
+}
+
+// readMetadataAt behaves like readMetadata, but additionally returns the logical (blockOffset, byteOffset)
+// position immediately following the bytes read, relative to firstBlock. This lets a caller chain further
+// reads - e.g. an inode header followed by its type-specific body - without having to know in advance
+// whether the earlier read crossed into a later metadata block.
+func readMetadataAt(r io.ReaderAt, firstBlock int64, initialBlockOffset uint32, byteOffset uint16, size int, d Decompressor) ([]byte, uint32, uint16, error) {
+	var (
+		b           []byte
+		blockOffset = initialBlockOffset
+		pos         = int(byteOffset)
+	)
+	for {
+		read, m, err := readMetadataBlock(r, firstBlock+int64(blockOffset), d)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		avail := m[pos:]
+		need := size - len(b)
+		if len(avail) >= need {
+			b = append(b, avail[:need]...)
+			pos += need
+			if pos == len(m) {
+				blockOffset += uint32(read)
+				pos = 0
+			}
+			return b, blockOffset, uint16(pos), nil
+		}
+		b = append(b, avail...)
+		blockOffset += uint32(read)
+		pos = 0
+		if len(b) >= size {
+			return b, blockOffset, uint16(pos), nil
+		}
+	}
+}
+
+// readDataBlock reads a single file data block from location. size is the block-size-list entry for this
+// block: bit 24 marks the block as stored uncompressed, and bits 0-23 are the on-disk size. A size of 0
+// indicates a sparse block, which is represented as blockSize zero bytes without touching the reader.
+func readDataBlock(r io.ReaderAt, location int64, size uint32, blockSize uint32, d Decompressor) ([]byte, error) {
+	if size == 0 {
+		return make([]byte, blockSize), nil
+	}
+	uncompressed := size&0x1000000 != 0
+	onDiskSize := size & 0xffffff
+	b := make([]byte, onDiskSize)
+	n, err := r.ReadAt(b, location)
+	if err != nil {
+		return nil, fmt.Errorf("could not read data block at %d: %v", location, err)
+	}
+	if n != len(b) {
+		return nil, fmt.Errorf("read %d instead of expected %d bytes for data block at %d", n, len(b), location)
+	}
+	if uncompressed {
+		return b, nil
+	}
+	out, err := d.Decompress(nil, b)
+	if err != nil {
+		return nil, fmt.Errorf("could not decompress data block at %d: %v", location, err)
+	}
+	return out, nil
+}
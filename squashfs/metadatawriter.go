@@ -0,0 +1,79 @@
+package squashfs
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// writeFramedMetadataBlock compresses chunk with c (falling back to storing it raw, with the
+// header's top bit set, if compression does not shrink it) and writes the resulting 2-byte
+// header plus payload to dst. It returns the number of bytes written.
+func writeFramedMetadataBlock(dst io.Writer, c Compressor, chunk []byte) (int, error) {
+	header := uint16(len(chunk)) | 0x8000
+	payload := chunk
+	if compressed, err := c.Compress(nil, chunk); err == nil && len(compressed) < len(chunk) {
+		header = uint16(len(compressed))
+		payload = compressed
+	}
+	hdr := make([]byte, 2)
+	binary.LittleEndian.PutUint16(hdr, header)
+	if _, err := dst.Write(hdr); err != nil {
+		return 0, err
+	}
+	if _, err := dst.Write(payload); err != nil {
+		return 0, err
+	}
+	return 2 + len(payload), nil
+}
+
+// metadataWriter accumulates bytes into a squashfs metadata-block stream (a repeated 2-byte
+// header plus payload, as readMetadataBlock/readMetadataAt expect to read back), flushing full
+// 8192-byte blocks as they fill. Each table (inode, directory, ...) that uses relative
+// (block, offset) addressing gets its own instance, writing to its own destination, so that one
+// table's block-offset accounting is never perturbed by bytes belonging to another.
+type metadataWriter struct {
+	w          io.Writer
+	compressor Compressor
+	buf        []byte
+	written    int64
+}
+
+func newMetadataWriter(w io.Writer, c Compressor) *metadataWriter {
+	return &metadataWriter{w: w, compressor: c}
+}
+
+// Add appends data to the table, flushing any now-complete blocks, and returns the (block, offset)
+// position - relative to the start of this table - at which data begins. data need not fit within a
+// single metadata block: readMetadataAt transparently chains across block boundaries when reading
+// it back.
+func (m *metadataWriter) Add(data []byte) (uint32, uint16, error) {
+	block := uint32(m.written)
+	offset := uint16(len(m.buf))
+	m.buf = append(m.buf, data...)
+	for len(m.buf) >= metadataSize {
+		if err := m.flush(m.buf[:metadataSize]); err != nil {
+			return 0, 0, err
+		}
+		m.buf = m.buf[metadataSize:]
+	}
+	return block, offset, nil
+}
+
+func (m *metadataWriter) flush(chunk []byte) error {
+	n, err := writeFramedMetadataBlock(m.w, m.compressor, chunk)
+	if err != nil {
+		return err
+	}
+	m.written += int64(n)
+	return nil
+}
+
+// Finish flushes any buffered remainder as a final, possibly short, block.
+func (m *metadataWriter) Finish() error {
+	if len(m.buf) == 0 {
+		return nil
+	}
+	err := m.flush(m.buf)
+	m.buf = nil
+	return err
+}
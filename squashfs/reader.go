@@ -0,0 +1,151 @@
+package squashfs
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// Reader reads a squashfs image from an io.ReaderAt and implements fs.FS, fs.ReadDirFS, fs.StatFS
+// and fs.ReadFileFS over its contents, in the same spirit as archive/zip.Reader.
+type Reader struct {
+	ra           io.ReaderAt
+	sb           *superblock
+	root         *entry
+	byPath       map[string]*entry
+	byInodeLoc   map[inodeLoc]*entry
+	fragments    []fragmentEntry
+	idTable      []uint32
+	xattrs       *xattrTable
+	exportTable  []uint64
+	decompressor Decompressor
+}
+
+var (
+	_ fs.FS         = (*Reader)(nil)
+	_ fs.ReadDirFS  = (*Reader)(nil)
+	_ fs.StatFS     = (*Reader)(nil)
+	_ fs.ReadFileFS = (*Reader)(nil)
+)
+
+// NewReader opens a squashfs image from ra and parses its superblock and directory tree.
+func NewReader(ra io.ReaderAt) (*Reader, error) {
+	sb, err := readSuperblock(ra)
+	if err != nil {
+		return nil, err
+	}
+	decompressor, err := newDecompressorInstance(sb.compression)
+	if err != nil {
+		return nil, fmt.Errorf("error creating decompressor: %v", err)
+	}
+	if sb.flags.compressorOptions {
+		if _, err := parseCompressorOptions(ra, sb.compression, decompressor); err != nil {
+			return nil, fmt.Errorf("error parsing compressor options: %v", err)
+		}
+	}
+
+	fragments, err := readFragmentTable(ra, sb.fragTableStart, sb.fragCount, decompressor)
+	if err != nil {
+		return nil, fmt.Errorf("error reading fragment table: %v", err)
+	}
+	idTable, err := readIDTable(ra, sb.idTableStart, sb.idCount, decompressor)
+	if err != nil {
+		return nil, fmt.Errorf("error reading id table: %v", err)
+	}
+	xattrs, err := readXattrTable(ra, sb.xattrTableStart, decompressor)
+	if err != nil {
+		return nil, fmt.Errorf("error reading xattr table: %v", err)
+	}
+	exportTable, err := readExportTable(ra, sb.exportTableStart, sb.inodeCount, decompressor)
+	if err != nil {
+		return nil, fmt.Errorf("error reading export table: %v", err)
+	}
+
+	root := &entry{name: ".", path: ".", inodeType: inodeBasicDirectory, block: sb.rootInodeBlock, offset: sb.rootInodeOffset}
+	if err := walkTree(ra, root, sb.inodeTableStart, sb.dirTableStart, decompressor, sb.blockSize, sb.inodeCount); err != nil {
+		return nil, fmt.Errorf("error walking directory tree: %v", err)
+	}
+
+	byPath := map[string]*entry{}
+	byInodeLoc := map[inodeLoc]*entry{}
+	var index func(e *entry)
+	index = func(e *entry) {
+		byPath[e.path] = e
+		byInodeLoc[inodeLoc{e.block, e.offset}] = e
+		for _, c := range e.children {
+			index(c)
+		}
+	}
+	index(root)
+
+	return &Reader{
+		ra:           ra,
+		sb:           sb,
+		root:         root,
+		byPath:       byPath,
+		byInodeLoc:   byInodeLoc,
+		fragments:    fragments,
+		idTable:      idTable,
+		xattrs:       xattrs,
+		exportTable:  exportTable,
+		decompressor: decompressor,
+	}, nil
+}
+
+func (r *Reader) lookup(op, name string) (*entry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	e, ok := r.byPath[name]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return e, nil
+}
+
+// Open implements fs.FS.
+func (r *Reader) Open(name string) (fs.File, error) {
+	e, err := r.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if e.isDir() {
+		return &dirFile{entry: e, r: r}, nil
+	}
+	return newRegularFile(r, e)
+}
+
+// Stat implements fs.StatFS.
+func (r *Reader) Stat(name string) (fs.FileInfo, error) {
+	e, err := r.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return fileInfo{e: e, r: r}, nil
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (r *Reader) ReadDir(name string) ([]fs.DirEntry, error) {
+	e, err := r.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !e.isDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fmt.Errorf("not a directory")}
+	}
+	entries := make([]fs.DirEntry, len(e.children))
+	for i, c := range e.children {
+		entries[i] = dirEntry{e: c, r: r}
+	}
+	return entries, nil
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (r *Reader) ReadFile(name string) ([]byte, error) {
+	f, err := r.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
@@ -0,0 +1,66 @@
+package squashfs
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+// TestReaderMultiBlockInodeTable builds an image with enough regular files that the (compressed)
+// inode table spans more than one metadata block, then reads it back. This guards against
+// readInodeHeader/parseFileInode falling back to a fixed per-block byte stride instead of
+// following the decompressor-aware block chain that readMetadataAt produces.
+func TestReaderMultiBlockInodeTable(t *testing.T) {
+	const fileCount = 600
+
+	fsys := fstest.MapFS{}
+	for i := 0; i < fileCount; i++ {
+		fsys[fmt.Sprintf("file%04d", i)] = &fstest.MapFile{
+			Data: []byte(fmt.Sprintf("content of file %d", i)),
+			Mode: 0644,
+		}
+	}
+
+	f, err := os.CreateTemp(t.TempDir(), "squashfs-multiblock-*.sqsh")
+	if err != nil {
+		t.Fatalf("could not create temp file: %v", err)
+	}
+	defer f.Close()
+
+	w, err := NewWriter(f, WriterOptions{})
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if err := w.WriteFS(fsys); err != nil {
+		t.Fatalf("WriteFS: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r, err := NewReader(f)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+
+	entries, err := r.ReadDir(".")
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != fileCount {
+		t.Fatalf("got %d directory entries, want %d", len(entries), fileCount)
+	}
+
+	for i := 0; i < fileCount; i++ {
+		name := fmt.Sprintf("file%04d", i)
+		want := fmt.Sprintf("content of file %d", i)
+		got, err := r.ReadFile(name)
+		if err != nil {
+			t.Fatalf("ReadFile(%s): %v", name, err)
+		}
+		if string(got) != want {
+			t.Fatalf("ReadFile(%s) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package squashfs
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// regularFile implements fs.File over a regular file's inode, streaming content by walking its
+// block list and, if present, its fragment tail. It also implements io.ReaderAt so callers can
+// randomly access large files without buffering their entire content.
+type regularFile struct {
+	r   *Reader
+	e   *entry
+	off int64
+}
+
+var (
+	_ fs.File     = (*regularFile)(nil)
+	_ io.ReaderAt = (*regularFile)(nil)
+)
+
+func newRegularFile(r *Reader, e *entry) (*regularFile, error) {
+	if e.file == nil {
+		return nil, fmt.Errorf("entry %s has no file content metadata", e.path)
+	}
+	return &regularFile{r: r, e: e}, nil
+}
+
+func (f *regularFile) Stat() (fs.FileInfo, error) { return fileInfo{e: f.e, r: f.r}, nil }
+func (f *regularFile) Close() error               { return nil }
+
+func (f *regularFile) Read(b []byte) (int, error) {
+	n, err := f.ReadAt(b, f.off)
+	f.off += int64(n)
+	return n, err
+}
+
+// ReadAt implements io.ReaderAt by locating the block(s) and, if needed, the fragment tail that
+// cover [off, off+len(b)), decompressing only those blocks.
+func (f *regularFile) ReadAt(b []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, &fs.PathError{Op: "readat", Path: f.e.path, Err: fs.ErrInvalid}
+	}
+	meta := f.e.file
+	fileSize := int64(meta.fileSize)
+	if off >= fileSize {
+		return 0, io.EOF
+	}
+
+	blockSize := int64(f.r.sb.blockSize)
+	var total int
+	for total < len(b) {
+		pos := off + int64(total)
+		if pos >= fileSize {
+			break
+		}
+		blockIdx := int(pos / blockSize)
+
+		var (
+			data []byte
+			err  error
+		)
+		if blockIdx < len(meta.blockSizes) {
+			data, err = f.readBlock(blockIdx)
+		} else {
+			data, err = f.r.fragmentTail(meta)
+		}
+		if err != nil {
+			return total, err
+		}
+
+		blockFileOffset := int64(blockIdx) * blockSize
+		start := int(pos - blockFileOffset)
+		if start > len(data) {
+			start = len(data)
+		}
+		n := copy(b[total:], data[start:])
+		if n == 0 {
+			break
+		}
+		total += n
+	}
+
+	var err error
+	if off+int64(total) >= fileSize {
+		err = io.EOF
+	}
+	return total, err
+}
+
+// readBlock decompresses the i'th block of the file's block list. Every block is a full blockSize
+// in length except possibly the last, which - when the file has no fragment - holds whatever
+// bytes remain after the preceding full blocks.
+func (f *regularFile) readBlock(i int) ([]byte, error) {
+	meta := f.e.file
+	size := meta.blockSizes[i]
+	location := int64(meta.blockStart) + meta.blockOffsets[i]
+	expected := f.r.sb.blockSize
+	if i == len(meta.blockSizes)-1 && meta.fragIndex == noFragment {
+		if rem := uint32(meta.fileSize % uint64(f.r.sb.blockSize)); rem != 0 {
+			expected = rem
+		}
+	}
+	return readDataBlock(f.r.ra, location, size, expected, f.r.decompressor)
+}
+
+// fragmentTail decompresses the fragment block holding meta's tail and returns just the bytes that
+// belong to meta, starting at its fragment offset.
+func (r *Reader) fragmentTail(meta *fileMeta) ([]byte, error) {
+	if meta.fragIndex == noFragment {
+		return nil, fmt.Errorf("file has no fragment tail")
+	}
+	if int(meta.fragIndex) >= len(r.fragments) {
+		return nil, fmt.Errorf("fragment index %d out of range of %d fragment table entries", meta.fragIndex, len(r.fragments))
+	}
+	frag := r.fragments[meta.fragIndex]
+	data, err := readDataBlock(r.ra, int64(frag.start), frag.size, r.sb.blockSize, r.decompressor)
+	if err != nil {
+		return nil, fmt.Errorf("could not read fragment block at %d: %v", frag.start, err)
+	}
+	tailLen := int(meta.fileSize % uint64(r.sb.blockSize))
+	start := int(meta.fragOffset)
+	if start > len(data) {
+		start = len(data)
+	}
+	end := start + tailLen
+	if end > len(data) {
+		end = len(data)
+	}
+	return data[start:end], nil
+}
@@ -0,0 +1,61 @@
+package squashfs
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestReadBlockSparseTailWithFragment verifies that a sparse (on-disk size 0) last block is
+// zero-filled for a full blockSize when the file also has a fragment tail. Only when a file has
+// no fragment does its last block-list entry hold a genuinely partial amount of bytes; with a
+// fragment present, every block-list entry - including a sparse one - represents a full block.
+func TestReadBlockSparseTailWithFragment(t *testing.T) {
+	const blockSize = 16
+
+	sb := &superblock{blockSize: blockSize, compression: 0}
+	r := &Reader{
+		sb: sb,
+		ra: bytes.NewReader([]byte{1, 2, 3, 4}),
+		fragments: []fragmentEntry{
+			{start: 0, size: blockSizeUncompressed | 4},
+		},
+	}
+	meta := &fileMeta{
+		blockStart:   0,
+		fileSize:     blockSize + 4, // one full block plus a fragment-held tail
+		fragIndex:    0,
+		fragOffset:   0,
+		blockSizes:   []uint32{0}, // sparse: the single full block holds no on-disk data
+		blockOffsets: []int64{0},
+	}
+	e := &entry{file: meta}
+	f := &regularFile{r: r, e: e}
+
+	data, err := f.readBlock(0)
+	if err != nil {
+		t.Fatalf("readBlock: %v", err)
+	}
+	if len(data) != blockSize {
+		t.Fatalf("got %d zero-filled bytes, want %d", len(data), blockSize)
+	}
+	for i, b := range data {
+		if b != 0 {
+			t.Fatalf("byte %d of sparse block = %#x, want 0", i, b)
+		}
+	}
+
+	// Read the whole file through the public API and confirm ReadAt never returns fewer bytes
+	// than requested with a nil error, as io.ReaderAt requires.
+	buf := make([]byte, meta.fileSize)
+	n, err := f.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		t.Fatalf("ReadAt: %v", err)
+	}
+	if n != int(meta.fileSize) {
+		t.Fatalf("ReadAt returned %d bytes, want %d", n, meta.fileSize)
+	}
+	if !bytes.Equal(buf[:blockSize], make([]byte, blockSize)) {
+		t.Fatalf("sparse block bytes were not all zero: %v", buf[:blockSize])
+	}
+}
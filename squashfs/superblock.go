@@ -0,0 +1,109 @@
+package squashfs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// superblock holds the parsed fields of a squashfs superblock, the 96-byte
+// header at the start of every image.
+type superblock struct {
+	inodeCount       uint32
+	modTime          time.Time
+	blockSize        uint32
+	fragCount        uint32
+	compression      uint16
+	blockLog         uint16
+	flags            *superblockFlags
+	idCount          uint16
+	major, minor     uint16
+	rootInodeBlock   uint32
+	rootInodeOffset  uint16
+	size             uint64
+	idTableStart     uint64
+	xattrTableStart  uint64
+	inodeTableStart  uint64
+	dirTableStart    uint64
+	fragTableStart   uint64
+	exportTableStart uint64
+}
+
+func parseInodeRef(ref uint64) (uint32, uint16) {
+	return uint32((ref >> 16) & 0xffffffff), uint16(ref & 0xffff)
+}
+
+// readSuperblock reads and validates the squashfs superblock at the start of r.
+func readSuperblock(r io.ReaderAt) (*superblock, error) {
+	b := make([]byte, superblockSize)
+	read, err := r.ReadAt(b, 0)
+	if err != nil {
+		return nil, fmt.Errorf("error reading superblock: %v", err)
+	}
+	if read != len(b) {
+		return nil, fmt.Errorf("failed to read superblock, read %d bytes instead of expected %d", read, len(b))
+	}
+	readMagic := binary.LittleEndian.Uint32(b[0:4])
+	if readMagic != magicByte {
+		return nil, fmt.Errorf("corrupt squashfs filesystem: magic bytes were %x instead of %x", readMagic, magicByte)
+	}
+
+	blockSize := binary.LittleEndian.Uint32(b[12:16])
+	blockLog := binary.LittleEndian.Uint16(b[22:24])
+	expectedLog := uint16(math.Log2(float64(blockSize)))
+	if expectedLog != blockLog {
+		return nil, fmt.Errorf("corrupt squashfs filesystem: log2 of blocksize was %d, expected %d", blockLog, expectedLog)
+	}
+
+	rootInodeRef := binary.LittleEndian.Uint64(b[32:40])
+	rootInodeBlock, rootInodeOffset := parseInodeRef(rootInodeRef)
+
+	return &superblock{
+		inodeCount:       binary.LittleEndian.Uint32(b[4:8]),
+		modTime:          time.Unix(int64(binary.LittleEndian.Uint32(b[8:12])), 0),
+		blockSize:        blockSize,
+		fragCount:        binary.LittleEndian.Uint32(b[16:20]),
+		compression:      binary.LittleEndian.Uint16(b[20:22]),
+		blockLog:         blockLog,
+		flags:            parseFlags(binary.LittleEndian.Uint16(b[24:26])),
+		idCount:          binary.LittleEndian.Uint16(b[26:28]),
+		major:            binary.LittleEndian.Uint16(b[28:30]),
+		minor:            binary.LittleEndian.Uint16(b[30:32]),
+		rootInodeBlock:   rootInodeBlock,
+		rootInodeOffset:  rootInodeOffset,
+		size:             binary.LittleEndian.Uint64(b[40:48]),
+		idTableStart:     binary.LittleEndian.Uint64(b[48:56]),
+		xattrTableStart:  binary.LittleEndian.Uint64(b[56:64]),
+		inodeTableStart:  binary.LittleEndian.Uint64(b[64:72]),
+		dirTableStart:    binary.LittleEndian.Uint64(b[72:80]),
+		fragTableStart:   binary.LittleEndian.Uint64(b[80:88]),
+		exportTableStart: binary.LittleEndian.Uint64(b[88:96]),
+	}, nil
+}
+
+// Info summarizes a squashfs image's superblock in a form convenient for
+// display or inspection by library consumers.
+type Info struct {
+	Compression   uint16
+	Version       string
+	ModTime       time.Time
+	BlockSize     uint32
+	Size          uint64
+	Inodes        uint32
+	FragmentCount uint32
+}
+
+// Info returns a summary of the image's superblock.
+func (r *Reader) Info() Info {
+	return Info{
+		Compression:   r.sb.compression,
+		Version:       fmt.Sprintf("%d.%d", r.sb.major, r.sb.minor),
+		ModTime:       r.sb.modTime,
+		BlockSize:     r.sb.blockSize,
+		Size:          r.sb.size,
+		Inodes:        r.sb.inodeCount,
+		FragmentCount: r.sb.fragCount,
+	}
+}
@@ -0,0 +1,156 @@
+package squashfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrCorruptDirectory is returned by walkTree when a directory's on-disk contents are internally
+// inconsistent, e.g. a directory inode whose parent_inode field does not match the directory it
+// was actually reached through.
+var ErrCorruptDirectory = errors.New("corrupt squashfs directory")
+
+// ErrInodeCycle is returned by walkTree when following a directory's children would revisit a
+// directory inode location already seen earlier in the same walk, which real squashfs images,
+// built from an acyclic source tree, never produce.
+var ErrInodeCycle = errors.New("cycle detected in squashfs directory tree")
+
+// entry is a single node - file, directory, symlink, device, etc. - discovered while walking the
+// directory tree. It is the in-memory representation behind both fs.FileInfo and fs.DirEntry.
+type entry struct {
+	name      string
+	path      string
+	inodeType inodeType
+	block     uint32
+	offset    uint16
+	dirBlock  uint32
+	dirOffset uint16
+	dirSize   uint16
+	header    *inodeHeader
+
+	// children is populated for directories only, in directory order.
+	children []*entry
+
+	// file is populated for regular files only, describing how to locate their content.
+	file *fileMeta
+}
+
+func (e *entry) isDir() bool {
+	return e.inodeType.isDir()
+}
+
+// pendingEntry pairs a queued entry with the inode number of the directory it was discovered
+// under, so that once the entry's own inode is read, a directory's parent_inode field can be
+// checked against the parent it actually came from.
+type pendingEntry struct {
+	e              *entry
+	parentInodeNum uint32
+}
+
+// walkTree reads the inode (and, for directories, the directory entries; for regular files, the
+// block list and fragment reference) for root and everything beneath it, populating each entry's
+// header, children and file fields.
+//
+// It works as an explicit queue rather than recursion, since a crafted or truncated image could
+// otherwise drive unbounded stack growth: real squashfs images can legitimately nest hundreds of
+// directories deep via container image layers, and a corrupt one could nest arbitrarily deeper.
+// For the same reason, it treats the image as untrusted input: it caps the number of inodes
+// visited at inodeCount, tracks visited directory inode locations to catch cycles a corrupt
+// directory entry could introduce (files are exempt, since legitimate hard links mean more than
+// one directory entry can validly point at the same file inode), and checks that each directory's
+// own parent_inode field names the directory it was actually reached through - returning
+// ErrInodeCycle or ErrCorruptDirectory rather than trusting the image to be well-formed.
+func walkTree(r io.ReaderAt, root *entry, inodeTable uint64, directoryTable uint64, d Decompressor, blockSize uint32, inodeCount uint32) error {
+	visitedDirs := map[inodeLoc]bool{{root.block, root.offset}: true}
+	queue := []pendingEntry{{e: root}}
+	var processed uint32
+
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		e := cur.e
+
+		processed++
+		if processed > inodeCount {
+			return fmt.Errorf("%w: walk visited more than the %d inodes recorded in the superblock", ErrCorruptDirectory, inodeCount)
+		}
+
+		header, bodyBlock, bodyOffset, err := readInodeHeader(r, int64(inodeTable), e.block, e.offset, d)
+		if err != nil {
+			return fmt.Errorf("error reading inode header at block %d offset %d: %v", e.block, e.offset, err)
+		}
+		e.header = header
+		e.inodeType = header.inodeType
+
+		switch {
+		case header.inodeType == inodeBasicFile || header.inodeType == inodeExtendedFile:
+			file, err := parseFileInode(r, int64(inodeTable), bodyBlock, bodyOffset, header.inodeType, blockSize, d)
+			if err != nil {
+				return fmt.Errorf("error reading file inode at block %d offset %d: %v", bodyBlock, bodyOffset, err)
+			}
+			e.file = file
+			continue
+		case !header.inodeType.isDir():
+			continue
+		}
+
+		dirBodySize := 16
+		if header.inodeType == inodeExtendedDirectory {
+			dirBodySize = 24
+		}
+		b, _, _, err := readMetadataAt(r, int64(inodeTable), bodyBlock, bodyOffset, dirBodySize, d)
+		if err != nil {
+			return fmt.Errorf("error reading inode body at block %d offset %d: %v", bodyBlock, bodyOffset, err)
+		}
+		dirBlockIndex, dirSize, offset, parentInode := parseDirectoryInode(b, header.inodeType)
+		e.dirBlock = dirBlockIndex
+		e.dirOffset = offset
+		e.dirSize = dirSize
+
+		if e != root && parentInode != cur.parentInodeNum {
+			return fmt.Errorf("%w: %s has parent_inode %d, but was reached through inode %d", ErrCorruptDirectory, e.path, parentInode, cur.parentInodeNum)
+		}
+
+		if dirSize == 0 {
+			continue
+		}
+
+		dirBytes, err := readMetadata(r, int64(directoryTable), dirBlockIndex, offset, int(dirSize), d)
+		if err != nil {
+			return fmt.Errorf("error reading directory for %s: %v", e.path, err)
+		}
+		children, err := parseDirectory(e.path, dirBytes)
+		if err != nil {
+			return fmt.Errorf("error parsing directory at %s: %v", e.path, err)
+		}
+
+		for _, child := range children {
+			if !child.isDir() {
+				continue
+			}
+			loc := inodeLoc{child.block, child.offset}
+			if visitedDirs[loc] {
+				return fmt.Errorf("%w: %s revisits directory inode at block %d offset %d", ErrInodeCycle, child.path, child.block, child.offset)
+			}
+			visitedDirs[loc] = true
+		}
+
+		e.children = children
+		for _, child := range children {
+			queue = append(queue, pendingEntry{e: child, parentInodeNum: header.index})
+		}
+	}
+	return nil
+}
+
+// permMode returns the os.FileMode bits (permissions plus type bit) for the entry, falling back to
+// a conservative default if the inode header has not been read.
+func (e *entry) permMode() os.FileMode {
+	var perm os.FileMode
+	if e.header != nil {
+		perm = e.header.mode & os.ModePerm
+	}
+	return e.inodeType.fileMode(perm)
+}
@@ -0,0 +1,101 @@
+package squashfs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// uncompressedMetadataBlock wraps content as a single raw (not-compressed) squashfs metadata
+// block: a 2-byte header with the "stored uncompressed" bit set, followed by content itself.
+func uncompressedMetadataBlock(content []byte) []byte {
+	header := make([]byte, 2)
+	binary.LittleEndian.PutUint16(header, uint16(len(content))|0x8000)
+	return append(header, content...)
+}
+
+// basicDirInode encodes a 32-byte basic directory inode: the common 16-byte header followed by the
+// 16-byte basic-directory body.
+func basicDirInode(inodeNumber uint32, dirBlockIndex uint32, dirSize uint16, dirOffset uint16, parentInode uint32) []byte {
+	b := make([]byte, inodeHeaderSize+16)
+	binary.LittleEndian.PutUint16(b[0:2], uint16(inodeBasicDirectory))
+	binary.LittleEndian.PutUint32(b[12:16], inodeNumber)
+	body := b[inodeHeaderSize:]
+	binary.LittleEndian.PutUint32(body[0:4], dirBlockIndex)
+	binary.LittleEndian.PutUint16(body[8:10], dirSize)
+	binary.LittleEndian.PutUint16(body[10:12], dirOffset)
+	binary.LittleEndian.PutUint32(body[12:16], parentInode)
+	return b
+}
+
+// dirListing encodes a single directory_header/directory_entry group naming one child, all of
+// whose inode lives at (childBlock, childOffset) within the inode table.
+func dirListing(childBlock uint32, childOffset uint16, childType inodeType, name string) []byte {
+	hdr := make([]byte, dirHeaderSize)
+	binary.LittleEndian.PutUint32(hdr[0:4], 0) // count-1: a single entry
+	binary.LittleEndian.PutUint32(hdr[4:8], childBlock)
+
+	entry := make([]byte, dirEntryMinSize+len(name))
+	binary.LittleEndian.PutUint16(entry[0:2], childOffset)
+	binary.LittleEndian.PutUint16(entry[4:6], uint16(childType))
+	binary.LittleEndian.PutUint16(entry[6:8], uint16(len(name)-1))
+	copy(entry[8:], name)
+
+	return append(hdr, entry...)
+}
+
+// TestWalkTreeDetectsInodeCycle builds a crafted image where a subdirectory's listing points back
+// at the root directory's own inode location, and checks that walkTree refuses to follow it back
+// in rather than looping forever.
+func TestWalkTreeDetectsInodeCycle(t *testing.T) {
+	const rootInodeNumber = 100
+
+	rootListing := dirListing(0, 32, inodeBasicDirectory, "child")
+	loopListing := dirListing(0, 0, inodeBasicDirectory, "loop") // points back at root's own (block, offset)
+
+	dirTable := uncompressedMetadataBlock(append(append([]byte{}, rootListing...), loopListing...))
+
+	root := basicDirInode(rootInodeNumber, 0, uint16(len(rootListing)), 0, 0)
+	child := basicDirInode(200, 0, uint16(len(loopListing)), uint16(len(rootListing)), rootInodeNumber)
+	inodeTable := uncompressedMetadataBlock(append(append([]byte{}, root...), child...))
+
+	img := bytes.NewReader(append(append([]byte{}, inodeTable...), dirTable...))
+	inodeTableStart := int64(0)
+	dirTableStart := int64(len(inodeTable))
+
+	rootEntry := &entry{name: ".", path: "."}
+	err := walkTree(img, rootEntry, uint64(inodeTableStart), uint64(dirTableStart), &gzipDecompressor{}, 131072, 2)
+	if !errors.Is(err, ErrInodeCycle) {
+		t.Fatalf("walkTree() error = %v, want ErrInodeCycle", err)
+	}
+}
+
+// TestWalkTreeDetectsParentInodeMismatch builds a crafted image where a subdirectory's
+// parent_inode field names an inode other than the one it was actually reached through, and
+// checks that walkTree rejects it instead of silently accepting the inconsistency.
+func TestWalkTreeDetectsParentInodeMismatch(t *testing.T) {
+	const rootInodeNumber = 100
+	const wrongParentInodeNumber = 999
+
+	rootListing := dirListing(0, 32, inodeBasicDirectory, "child")
+	childListing := dirListing(0, 0, inodeBasicDirectory, "empty")
+
+	dirTable := uncompressedMetadataBlock(append(append([]byte{}, rootListing...), childListing...))
+
+	root := basicDirInode(rootInodeNumber, 0, uint16(len(rootListing)), 0, 0)
+	// child claims a parent_inode that does not match rootInodeNumber, the inode it was actually
+	// discovered through.
+	child := basicDirInode(200, 0, uint16(len(childListing)), uint16(len(rootListing)), wrongParentInodeNumber)
+	inodeTable := uncompressedMetadataBlock(append(append([]byte{}, root...), child...))
+
+	img := bytes.NewReader(append(append([]byte{}, inodeTable...), dirTable...))
+	inodeTableStart := int64(0)
+	dirTableStart := int64(len(inodeTable))
+
+	rootEntry := &entry{name: ".", path: "."}
+	err := walkTree(img, rootEntry, uint64(inodeTableStart), uint64(dirTableStart), &gzipDecompressor{}, 131072, 2)
+	if !errors.Is(err, ErrCorruptDirectory) {
+		t.Fatalf("walkTree() error = %v, want ErrCorruptDirectory", err)
+	}
+}
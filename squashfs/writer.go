@@ -0,0 +1,670 @@
+package squashfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WriterOptions configures the image a Writer produces. There is no option to emit an xattr
+// table: Writer does not support extended attributes (see the Writer doc comment).
+type WriterOptions struct {
+	// BlockSize is the data block size in bytes; it must be a power of two. Defaults to 131072
+	// (128KB), matching mksquashfs's default.
+	BlockSize uint32
+	// Compression selects, by id, the compressor used for every block in the image. Defaults to
+	// gzip (id 1).
+	Compression uint16
+	// NoFragments disables fragment packing: every file's tail is stored as its own, possibly
+	// short, data block instead of being packed into a shared fragment block.
+	NoFragments bool
+	// AlwaysFragments is accepted for API parity with mksquashfs; beyond setting the matching
+	// superblock flag it changes nothing here: this Writer already routes every file's tail,
+	// however small, through a fragment unless NoFragments is set.
+	AlwaysFragments bool
+	// Exportable builds an export table, enabling Reader.LookupInode on images this Writer
+	// produces.
+	Exportable bool
+	// Dedup detects files with identical content and stores the data only once. Enabling it
+	// buffers each file's content - not the whole filesystem - in memory so it can be hashed
+	// before any of its blocks are written.
+	Dedup bool
+}
+
+func (o WriterOptions) withDefaults() WriterOptions {
+	if o.BlockSize == 0 {
+		o.BlockSize = 131072
+	}
+	if o.Compression == 0 {
+		o.Compression = compressionGzip
+	}
+	return o
+}
+
+// writerNode is the in-memory representation of a single tree entry - file or directory - while an
+// image is being built. Only directories and regular files are supported: every other type a
+// source might offer (symlinks, devices, ...) is rejected by WriteTar/WriteFS, since Reader has no
+// way to expose them once read back.
+type writerNode struct {
+	name    string
+	isDir   bool
+	mode    os.FileMode
+	uid     uint32
+	gid     uint32
+	modTime time.Time
+
+	children []*writerNode
+
+	// file-only fields, populated by writeFileContent.
+	fileSize   uint64
+	blockStart uint64
+	blockSizes []uint32
+	fragIndex  uint32
+	fragOffset uint32
+
+	// assigned while the tree is written out: inodeNumber by assignInodeNumbers, the rest by
+	// writeFileInode/writeDirInode.
+	inodeNumber uint32
+	inodeBlock  uint32
+	inodeOffset uint16
+	writtenType inodeType
+}
+
+// dedupRecord remembers where an already-written file's content lives, keyed by its content hash,
+// so a later identical file can reuse it instead of writing new blocks.
+type dedupRecord struct {
+	blockStart uint64
+	blockSizes []uint32
+	fragIndex  uint32
+	fragOffset uint32
+}
+
+// Writer builds a squashfs 4.0 image on an io.WriteSeeker, streaming file content as it is added
+// (WriteTar, WriteFS) rather than assembling the whole filesystem in memory first - the same
+// streaming-first shape hcsshim's tar2ext4 takes for ext4. Only the small metadata tables (inode,
+// directory, fragment, id, export) are buffered, and only until Close.
+//
+// Writer does not emit an xattr table: extended attributes (e.g. a tar entry's PAXRecords) are
+// discarded rather than written, and images it produces always have the noXattrs superblock flag
+// set. A Reader opening such an image simply sees no xattrs on any file, the same as it would for
+// an upstream mksquashfs image built with -no-xattrs.
+type Writer struct {
+	w          io.WriteSeeker
+	opts       WriterOptions
+	compressor Compressor
+
+	root *writerNode
+	dirs map[string]*writerNode
+
+	fragBuf   []byte
+	fragments []fragmentEntry
+
+	dedup map[[sha256.Size]byte]dedupRecord
+
+	idIndex map[uint32]uint16
+	ids     []uint32
+
+	inodeCount uint32
+	closed     bool
+}
+
+// NewWriter prepares a Writer that will produce a squashfs image on w. Callers add content with
+// WriteTar and/or WriteFS, in any mix, and must call Close to finish the image.
+func NewWriter(w io.WriteSeeker, opts WriterOptions) (*Writer, error) {
+	opts = opts.withDefaults()
+	c, err := getCompressor(opts.Compression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Seek(superblockSize, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("could not seek past superblock: %v", err)
+	}
+	root := &writerNode{name: ".", isDir: true, mode: os.ModeDir | 0755}
+	return &Writer{
+		w:          w,
+		opts:       opts,
+		compressor: c,
+		root:       root,
+		dirs:       map[string]*writerNode{".": root},
+		dedup:      map[[sha256.Size]byte]dedupRecord{},
+		idIndex:    map[uint32]uint16{},
+	}, nil
+}
+
+func (w *Writer) tell() (int64, error) {
+	return w.w.Seek(0, io.SeekCurrent)
+}
+
+func (w *Writer) idIdx(id uint32) uint16 {
+	if idx, ok := w.idIndex[id]; ok {
+		return idx
+	}
+	idx := uint16(len(w.ids))
+	w.idIndex[id] = idx
+	w.ids = append(w.ids, id)
+	return idx
+}
+
+// ensureDir returns the node for the directory named by parts (slash-separated, relative to
+// root), creating any missing intermediate directories with a conservative default mode: tar
+// streams and fs.FS walks do not always visit every ancestor directory explicitly.
+func (w *Writer) ensureDir(parts []string) *writerNode {
+	node := w.root
+	cur := "."
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		cur = fsJoin(cur, part)
+		child, ok := w.dirs[cur]
+		if !ok {
+			child = &writerNode{name: part, isDir: true, mode: os.ModeDir | 0755}
+			node.children = append(node.children, child)
+			w.dirs[cur] = child
+		}
+		node = child
+	}
+	return node
+}
+
+// addNode records a single tar or fs.FS entry at p. content is read in full and consumed when it
+// is not nil; pass a nil content for directories.
+func (w *Writer) addNode(p string, isDir bool, mode os.FileMode, uid, gid uint32, modTime time.Time, size int64, content io.Reader) error {
+	clean := path.Clean(strings.Trim(p, "/"))
+	if clean == "." || clean == "" {
+		w.root.mode = os.ModeDir | mode.Perm()
+		w.root.uid, w.root.gid, w.root.modTime = uid, gid, modTime
+		return nil
+	}
+	dir, name := path.Split(clean)
+	var parts []string
+	if dir = strings.Trim(dir, "/"); dir != "" {
+		parts = strings.Split(dir, "/")
+	}
+	parent := w.ensureDir(parts)
+
+	node, existing := w.dirs[clean]
+	if !existing {
+		node = &writerNode{name: name}
+		parent.children = append(parent.children, node)
+	}
+	node.uid, node.gid, node.modTime = uid, gid, modTime
+	if isDir {
+		node.isDir = true
+		node.mode = os.ModeDir | mode.Perm()
+		w.dirs[clean] = node
+		return nil
+	}
+	if existing {
+		return fmt.Errorf("duplicate entry for %s", clean)
+	}
+	node.mode = mode.Perm()
+	return w.writeFileContent(node, size, content)
+}
+
+// writeFileContent streams r's size bytes into data blocks (and, usually, a fragment tail),
+// recording where they land in node. With Dedup enabled, content is buffered and hashed first so
+// an identical file can reuse an earlier one's blocks instead of writing new ones.
+func (w *Writer) writeFileContent(n *writerNode, size int64, r io.Reader) error {
+	n.fileSize = uint64(size)
+	if !w.opts.Dedup {
+		return w.streamBlocks(n, r, size)
+	}
+
+	buf := make([]byte, size)
+	if size > 0 {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return fmt.Errorf("error reading content for %s: %v", n.name, err)
+		}
+	}
+	sum := sha256.Sum256(buf)
+	if dup, ok := w.dedup[sum]; ok {
+		n.blockStart, n.blockSizes = dup.blockStart, dup.blockSizes
+		n.fragIndex, n.fragOffset = dup.fragIndex, dup.fragOffset
+		return nil
+	}
+	if err := w.streamBlocks(n, bytes.NewReader(buf), size); err != nil {
+		return err
+	}
+	w.dedup[sum] = dedupRecord{blockStart: n.blockStart, blockSizes: n.blockSizes, fragIndex: n.fragIndex, fragOffset: n.fragOffset}
+	return nil
+}
+
+// streamBlocks reads size bytes from r in BlockSize chunks, writing each full chunk out as its own
+// data block and routing the final, short chunk (if any) into a fragment, unless NoFragments is
+// set, in which case it too becomes its own data block.
+func (w *Writer) streamBlocks(n *writerNode, r io.Reader, size int64) error {
+	blockStart, err := w.tell()
+	if err != nil {
+		return err
+	}
+	n.blockStart = uint64(blockStart)
+	n.fragIndex = noFragment
+
+	blockSize := int64(w.opts.BlockSize)
+	buf := make([]byte, blockSize)
+	remaining := size
+	for remaining > 0 {
+		chunkLen := blockSize
+		if remaining < blockSize {
+			chunkLen = remaining
+		}
+		if _, err := io.ReadFull(r, buf[:chunkLen]); err != nil {
+			return fmt.Errorf("error reading content for %s: %v", n.name, err)
+		}
+		chunk := buf[:chunkLen]
+		if chunkLen < blockSize && !w.opts.NoFragments {
+			idx, off, err := w.addFragment(chunk)
+			if err != nil {
+				return err
+			}
+			n.fragIndex, n.fragOffset = idx, off
+			return nil
+		}
+		sizeField, err := w.writeDataBlock(chunk)
+		if err != nil {
+			return err
+		}
+		n.blockSizes = append(n.blockSizes, sizeField)
+		remaining -= chunkLen
+	}
+	return nil
+}
+
+// writeDataBlock compresses chunk (falling back to storing it raw if compression does not shrink
+// it) and writes it at the current position, returning the block-list size field: bits 0-23 are
+// the on-disk size, bit 24 marks it as stored uncompressed.
+func (w *Writer) writeDataBlock(chunk []byte) (uint32, error) {
+	payload := chunk
+	var sizeField uint32
+	if compressed, err := w.compressor.Compress(nil, chunk); err == nil && len(compressed) < len(chunk) {
+		payload = compressed
+		sizeField = uint32(len(payload))
+	} else {
+		sizeField = uint32(len(payload)) | blockSizeUncompressed
+	}
+	if _, err := w.w.Write(payload); err != nil {
+		return 0, fmt.Errorf("error writing data block: %v", err)
+	}
+	return sizeField, nil
+}
+
+// addFragment appends tail to the pending fragment block, flushing it first if tail would not
+// fit, and returns the fragment index and offset the caller's file should record. Every tail
+// added before a flush shares the index the pending block will get once it is written.
+func (w *Writer) addFragment(tail []byte) (uint32, uint32, error) {
+	if len(w.fragBuf)+len(tail) > int(w.opts.BlockSize) {
+		if err := w.flushFragments(); err != nil {
+			return 0, 0, err
+		}
+	}
+	idx := uint32(len(w.fragments))
+	off := uint32(len(w.fragBuf))
+	w.fragBuf = append(w.fragBuf, tail...)
+	return idx, off, nil
+}
+
+// flushFragments writes out the pending fragment block, if any, recording its fragmentEntry.
+func (w *Writer) flushFragments() error {
+	if len(w.fragBuf) == 0 {
+		return nil
+	}
+	location, err := w.tell()
+	if err != nil {
+		return err
+	}
+	sizeField, err := w.writeDataBlock(w.fragBuf)
+	if err != nil {
+		return err
+	}
+	w.fragments = append(w.fragments, fragmentEntry{start: uint64(location), size: sizeField})
+	w.fragBuf = nil
+	return nil
+}
+
+// assignInodeNumbers numbers every node in pre-order (parent before children), independently of
+// the order their bytes are later written to the inode table: a directory's body must record its
+// parent's inode number, but the parent's own table entry is necessarily written after its
+// children's, so the number itself has to be decided first.
+func (w *Writer) assignInodeNumbers() {
+	next := uint32(1)
+	var walk func(n *writerNode)
+	walk = func(n *writerNode) {
+		n.inodeNumber = next
+		next++
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	walk(w.root)
+	w.inodeCount = next - 1
+}
+
+// encodeInodeHeader builds the 16-byte header common to every inode type.
+func (w *Writer) encodeInodeHeader(t inodeType, n *writerNode) []byte {
+	b := make([]byte, inodeHeaderSize)
+	binary.LittleEndian.PutUint16(b[0:2], uint16(t))
+	binary.LittleEndian.PutUint16(b[2:4], uint16(n.mode.Perm()))
+	binary.LittleEndian.PutUint16(b[4:6], w.idIdx(n.uid))
+	binary.LittleEndian.PutUint16(b[6:8], w.idIdx(n.gid))
+	binary.LittleEndian.PutUint32(b[8:12], uint32(n.modTime.Unix()))
+	binary.LittleEndian.PutUint32(b[12:16], n.inodeNumber)
+	return b
+}
+
+// writeFileInode writes n's basic file inode - header, block-start/fragment/size fields, then the
+// block-size list - into the inode table, recording where it landed.
+func (w *Writer) writeFileInode(imw *metadataWriter, n *writerNode) error {
+	body := make([]byte, basicFileFixedSize+len(n.blockSizes)*4)
+	binary.LittleEndian.PutUint32(body[0:4], uint32(n.blockStart))
+	binary.LittleEndian.PutUint32(body[4:8], n.fragIndex)
+	binary.LittleEndian.PutUint32(body[8:12], n.fragOffset)
+	binary.LittleEndian.PutUint32(body[12:16], uint32(n.fileSize))
+	for i, s := range n.blockSizes {
+		binary.LittleEndian.PutUint32(body[basicFileFixedSize+i*4:basicFileFixedSize+i*4+4], s)
+	}
+
+	n.writtenType = inodeBasicFile
+	block, offset, err := imw.Add(append(w.encodeInodeHeader(inodeBasicFile, n), body...))
+	if err != nil {
+		return fmt.Errorf("error writing inode for %s: %v", n.name, err)
+	}
+	n.inodeBlock, n.inodeOffset = block, offset
+	return nil
+}
+
+// writeDirInode writes n's directory entries into the directory table, then its basic directory
+// inode - header, directory-table pointer, entry-bytes size, and parent inode number - into the
+// inode table.
+func (w *Writer) writeDirInode(imw, dmw *metadataWriter, n *writerNode, parentInodeNumber uint32) error {
+	entries := w.buildDirEntries(n.children)
+	dirBlock, dirOffset, err := dmw.Add(entries)
+	if err != nil {
+		return fmt.Errorf("error writing directory entries for %s: %v", n.name, err)
+	}
+
+	body := make([]byte, 16)
+	binary.LittleEndian.PutUint32(body[0:4], dirBlock)
+	binary.LittleEndian.PutUint32(body[4:8], 1) // nlink; this Writer does not model hard links
+	binary.LittleEndian.PutUint16(body[8:10], uint16(len(entries)))
+	binary.LittleEndian.PutUint16(body[10:12], dirOffset)
+	binary.LittleEndian.PutUint32(body[12:16], parentInodeNumber)
+
+	n.writtenType = inodeBasicDirectory
+	block, offset, err := imw.Add(append(w.encodeInodeHeader(inodeBasicDirectory, n), body...))
+	if err != nil {
+		return fmt.Errorf("error writing inode for %s: %v", n.name, err)
+	}
+	n.inodeBlock, n.inodeOffset = block, offset
+	return nil
+}
+
+// buildDirEntries encodes children - already sorted by name, as squashfs directories require - as
+// a run of directory_header/directory_entry groups, starting a new header whenever the next
+// child's inode lives in a different inode-table block or the current group has reached
+// maxDirEntries.
+func (w *Writer) buildDirEntries(children []*writerNode) []byte {
+	var out []byte
+	for i := 0; i < len(children); {
+		j := i + 1
+		for j < len(children) && j-i < maxDirEntries && children[j].inodeBlock == children[i].inodeBlock {
+			j++
+		}
+		group := children[i:j]
+
+		hdr := make([]byte, dirHeaderSize)
+		binary.LittleEndian.PutUint32(hdr[0:4], uint32(len(group)-1))
+		binary.LittleEndian.PutUint32(hdr[4:8], group[0].inodeBlock)
+		binary.LittleEndian.PutUint32(hdr[8:12], group[0].inodeNumber)
+		out = append(out, hdr...)
+
+		for _, c := range group {
+			entry := make([]byte, dirEntryMinSize+len(c.name))
+			binary.LittleEndian.PutUint16(entry[0:2], c.inodeOffset)
+			binary.LittleEndian.PutUint16(entry[2:4], uint16(int32(c.inodeNumber)-int32(group[0].inodeNumber)))
+			binary.LittleEndian.PutUint16(entry[4:6], uint16(c.writtenType))
+			binary.LittleEndian.PutUint16(entry[6:8], uint16(len(c.name)-1))
+			copy(entry[8:], c.name)
+			out = append(out, entry...)
+		}
+		i = j
+	}
+	return out
+}
+
+// writeIndirectTable writes packed (already-serialized fixed-size records) out as a squashfs
+// indirect table: a run of metadata blocks holding the records, followed by a contiguous array of
+// uint64 absolute file offsets pointing at each one - the same shape readFragmentTable,
+// readIDTable and readExportTable expect. It returns the offset of the pointer array, i.e. the
+// table's start as stored in the superblock.
+func (w *Writer) writeIndirectTable(packed []byte) (int64, error) {
+	var starts []uint64
+	for off := 0; off < len(packed); off += metadataSize {
+		end := off + metadataSize
+		if end > len(packed) {
+			end = len(packed)
+		}
+		loc, err := w.tell()
+		if err != nil {
+			return 0, err
+		}
+		if _, err := writeFramedMetadataBlock(w.w, w.compressor, packed[off:end]); err != nil {
+			return 0, fmt.Errorf("error writing table block: %v", err)
+		}
+		starts = append(starts, uint64(loc))
+	}
+
+	indexStart, err := w.tell()
+	if err != nil {
+		return 0, err
+	}
+	ptrBytes := make([]byte, len(starts)*8)
+	for i, s := range starts {
+		binary.LittleEndian.PutUint64(ptrBytes[i*8:i*8+8], s)
+	}
+	if _, err := w.w.Write(ptrBytes); err != nil {
+		return 0, fmt.Errorf("error writing table index: %v", err)
+	}
+	return indexStart, nil
+}
+
+// superblockInfo holds the fields writeSuperblock needs that Close computes only once everything
+// else has been written.
+type superblockInfo struct {
+	inodeCount       uint32
+	modTime          time.Time
+	fragCount        uint32
+	blockLog         uint16
+	rootInodeRef     uint64
+	size             uint64
+	idTableStart     uint64
+	xattrTableStart  uint64
+	inodeTableStart  uint64
+	dirTableStart    uint64
+	fragTableStart   uint64
+	exportTableStart uint64
+}
+
+// flagsBits encodes the superblock flags implied by opts. This Writer never emits an xattr table,
+// so noXattrs (0x0200) is always set.
+func (w *Writer) flagsBits() uint16 {
+	f := uint16(0x0200)
+	if w.opts.NoFragments {
+		f |= 0x0010
+	}
+	if w.opts.AlwaysFragments {
+		f |= 0x0020
+	}
+	if w.opts.Dedup {
+		f |= 0x0040
+	}
+	if w.opts.Exportable {
+		f |= 0x0080
+	}
+	return f
+}
+
+func (w *Writer) writeSuperblock(info superblockInfo) error {
+	b := make([]byte, superblockSize)
+	binary.LittleEndian.PutUint32(b[0:4], magicByte)
+	binary.LittleEndian.PutUint32(b[4:8], info.inodeCount)
+	binary.LittleEndian.PutUint32(b[8:12], uint32(info.modTime.Unix()))
+	binary.LittleEndian.PutUint32(b[12:16], w.opts.BlockSize)
+	binary.LittleEndian.PutUint32(b[16:20], info.fragCount)
+	binary.LittleEndian.PutUint16(b[20:22], w.opts.Compression)
+	binary.LittleEndian.PutUint16(b[22:24], info.blockLog)
+	binary.LittleEndian.PutUint16(b[24:26], w.flagsBits())
+	binary.LittleEndian.PutUint16(b[26:28], uint16(len(w.ids)))
+	binary.LittleEndian.PutUint16(b[28:30], 4) // major
+	binary.LittleEndian.PutUint16(b[30:32], 0) // minor
+	binary.LittleEndian.PutUint64(b[32:40], info.rootInodeRef)
+	binary.LittleEndian.PutUint64(b[40:48], info.size)
+	binary.LittleEndian.PutUint64(b[48:56], info.idTableStart)
+	binary.LittleEndian.PutUint64(b[56:64], info.xattrTableStart)
+	binary.LittleEndian.PutUint64(b[64:72], info.inodeTableStart)
+	binary.LittleEndian.PutUint64(b[72:80], info.dirTableStart)
+	binary.LittleEndian.PutUint64(b[80:88], info.fragTableStart)
+	binary.LittleEndian.PutUint64(b[88:96], info.exportTableStart)
+
+	if _, err := w.w.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("error seeking to superblock: %v", err)
+	}
+	if _, err := w.w.Write(b); err != nil {
+		return fmt.Errorf("error writing superblock: %v", err)
+	}
+	return nil
+}
+
+// Close finishes the image: it writes the inode, directory, fragment, id and (if Exportable)
+// export tables, then seeks back and writes the superblock with the offsets and counts they came
+// out to. It must be called exactly once, after all content has been added.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if err := w.flushFragments(); err != nil {
+		return err
+	}
+	w.assignInodeNumbers()
+
+	// The inode and directory tables are built as the tree is walked post-order (a directory's
+	// own inode, and its parent's directory entry for it, both need its children's table
+	// positions first), but each table must land as one contiguous run in the image - so each is
+	// assembled into its own buffer here and copied out, in order, only once the walk is done.
+	inodeBuf := &bytes.Buffer{}
+	dirBuf := &bytes.Buffer{}
+	imw := newMetadataWriter(inodeBuf, w.compressor)
+	dmw := newMetadataWriter(dirBuf, w.compressor)
+
+	var walk func(n *writerNode, parentInodeNumber uint32) error
+	walk = func(n *writerNode, parentInodeNumber uint32) error {
+		sort.Slice(n.children, func(i, j int) bool { return n.children[i].name < n.children[j].name })
+		for _, c := range n.children {
+			if c.isDir {
+				if err := walk(c, n.inodeNumber); err != nil {
+					return err
+				}
+			}
+		}
+		for _, c := range n.children {
+			if !c.isDir {
+				if err := w.writeFileInode(imw, c); err != nil {
+					return err
+				}
+			}
+		}
+		return w.writeDirInode(imw, dmw, n, parentInodeNumber)
+	}
+	if err := walk(w.root, w.root.inodeNumber); err != nil {
+		return fmt.Errorf("error building inode/directory tables: %v", err)
+	}
+	if err := imw.Finish(); err != nil {
+		return fmt.Errorf("error finishing inode table: %v", err)
+	}
+	if err := dmw.Finish(); err != nil {
+		return fmt.Errorf("error finishing directory table: %v", err)
+	}
+
+	inodeTableStart, err := w.tell()
+	if err != nil {
+		return err
+	}
+	if _, err := w.w.Write(inodeBuf.Bytes()); err != nil {
+		return fmt.Errorf("error writing inode table: %v", err)
+	}
+	dirTableStart, err := w.tell()
+	if err != nil {
+		return err
+	}
+	if _, err := w.w.Write(dirBuf.Bytes()); err != nil {
+		return fmt.Errorf("error writing directory table: %v", err)
+	}
+
+	fragPacked := make([]byte, len(w.fragments)*fragmentEntrySize)
+	for i, f := range w.fragments {
+		binary.LittleEndian.PutUint64(fragPacked[i*fragmentEntrySize:i*fragmentEntrySize+8], f.start)
+		binary.LittleEndian.PutUint32(fragPacked[i*fragmentEntrySize+8:i*fragmentEntrySize+12], f.size)
+	}
+	fragTableStart, err := w.writeIndirectTable(fragPacked)
+	if err != nil {
+		return fmt.Errorf("error writing fragment table: %v", err)
+	}
+
+	idPacked := make([]byte, len(w.ids)*4)
+	for i, id := range w.ids {
+		binary.LittleEndian.PutUint32(idPacked[i*4:i*4+4], id)
+	}
+	idTableStart, err := w.writeIndirectTable(idPacked)
+	if err != nil {
+		return fmt.Errorf("error writing id table: %v", err)
+	}
+
+	exportTableStart := uint64(noTableSentinel)
+	if w.opts.Exportable {
+		exportPacked := make([]byte, w.inodeCount*8)
+		var index func(n *writerNode)
+		index = func(n *writerNode) {
+			ref := uint64(n.inodeBlock)<<16 | uint64(n.inodeOffset)
+			binary.LittleEndian.PutUint64(exportPacked[(n.inodeNumber-1)*8:(n.inodeNumber-1)*8+8], ref)
+			for _, c := range n.children {
+				index(c)
+			}
+		}
+		index(w.root)
+		start, err := w.writeIndirectTable(exportPacked)
+		if err != nil {
+			return fmt.Errorf("error writing export table: %v", err)
+		}
+		exportTableStart = uint64(start)
+	}
+
+	size, err := w.tell()
+	if err != nil {
+		return err
+	}
+
+	return w.writeSuperblock(superblockInfo{
+		inodeCount:       w.inodeCount,
+		modTime:          w.root.modTime,
+		fragCount:        uint32(len(w.fragments)),
+		blockLog:         uint16(math.Log2(float64(w.opts.BlockSize))),
+		rootInodeRef:     uint64(w.root.inodeBlock)<<16 | uint64(w.root.inodeOffset),
+		size:             uint64(size),
+		idTableStart:     uint64(idTableStart),
+		xattrTableStart:  noTableSentinel,
+		inodeTableStart:  uint64(inodeTableStart),
+		dirTableStart:    uint64(dirTableStart),
+		fragTableStart:   uint64(fragTableStart),
+		exportTableStart: exportTableStart,
+	})
+}
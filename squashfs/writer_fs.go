@@ -0,0 +1,33 @@
+package squashfs
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// WriteFS adds every entry found by walking fsys to the image. Only directories and regular files
+// are supported, matching what Reader can expose back; any other fs.FileMode type is an error.
+func (w *Writer) WriteFS(fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("error walking %s: %v", p, err)
+		}
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("error reading info for %s: %v", p, err)
+		}
+		switch {
+		case d.IsDir():
+			return w.addNode(p, true, info.Mode(), 0, 0, info.ModTime(), 0, nil)
+		case info.Mode().IsRegular():
+			f, err := fsys.Open(p)
+			if err != nil {
+				return fmt.Errorf("error opening %s: %v", p, err)
+			}
+			defer f.Close()
+			return w.addNode(p, false, info.Mode(), 0, 0, info.ModTime(), info.Size(), f)
+		default:
+			return fmt.Errorf("unsupported file type for %s", p)
+		}
+	})
+}
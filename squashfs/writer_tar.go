@@ -0,0 +1,34 @@
+package squashfs
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+)
+
+// WriteTar adds every entry read from tr to the image. Only directories and regular files are
+// supported, matching what Reader can expose back; any other tar entry type is an error.
+func (w *Writer) WriteTar(tr *tar.Reader) error {
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading tar header: %v", err)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := w.addNode(hdr.Name, true, os.FileMode(hdr.Mode), uint32(hdr.Uid), uint32(hdr.Gid), hdr.ModTime, 0, nil); err != nil {
+				return fmt.Errorf("error adding %s: %v", hdr.Name, err)
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := w.addNode(hdr.Name, false, os.FileMode(hdr.Mode), uint32(hdr.Uid), uint32(hdr.Gid), hdr.ModTime, hdr.Size, tr); err != nil {
+				return fmt.Errorf("error adding %s: %v", hdr.Name, err)
+			}
+		default:
+			return fmt.Errorf("unsupported tar entry type %v for %s", hdr.Typeflag, hdr.Name)
+		}
+	}
+}
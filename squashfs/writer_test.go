@@ -0,0 +1,127 @@
+package squashfs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+// buildImage writes fsys through a Writer configured with opts and returns a Reader opened on the
+// result.
+func buildImage(t *testing.T, fsys fstest.MapFS, opts WriterOptions) *Reader {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "squashfs-*.sqsh")
+	if err != nil {
+		t.Fatalf("could not create temp file: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	w, err := NewWriter(f, opts)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if err := w.WriteFS(fsys); err != nil {
+		t.Fatalf("WriteFS: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r, err := NewReader(f)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	return r
+}
+
+// TestWriterCompressionRoundTrip writes and reads back the same small filesystem under each
+// supported compressor, to catch a compressor/decompressor pair that round-trips incorrectly.
+func TestWriterCompressionRoundTrip(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":     &fstest.MapFile{Data: bytes.Repeat([]byte("hello squashfs "), 100), Mode: 0644},
+		"dir/b.txt": &fstest.MapFile{Data: []byte("small file"), Mode: 0644},
+	}
+
+	for _, compression := range []uint16{compressionGzip, compressionXZ, compressionLZ4, compressionZstd} {
+		compression := compression
+		t.Run(fmt.Sprintf("compression=%d", compression), func(t *testing.T) {
+			r := buildImage(t, fsys, WriterOptions{Compression: compression})
+			for name, mf := range fsys {
+				got, err := r.ReadFile(name)
+				if err != nil {
+					t.Fatalf("ReadFile(%s): %v", name, err)
+				}
+				if !bytes.Equal(got, mf.Data) {
+					t.Fatalf("ReadFile(%s) = %q, want %q", name, got, mf.Data)
+				}
+			}
+		})
+	}
+}
+
+// TestWriterDedup verifies that two files with identical content are correctly deduplicated - both
+// read back with the right content - rather than just trusting that the shared block pointers
+// happen to work.
+func TestWriterDedup(t *testing.T) {
+	content := bytes.Repeat([]byte("duplicate me "), 50)
+	fsys := fstest.MapFS{
+		"first":  &fstest.MapFile{Data: content, Mode: 0644},
+		"second": &fstest.MapFile{Data: content, Mode: 0644},
+		"other":  &fstest.MapFile{Data: []byte("not a duplicate"), Mode: 0644},
+	}
+
+	r := buildImage(t, fsys, WriterOptions{Dedup: true})
+	for name, mf := range fsys {
+		got, err := r.ReadFile(name)
+		if err != nil {
+			t.Fatalf("ReadFile(%s): %v", name, err)
+		}
+		if !bytes.Equal(got, mf.Data) {
+			t.Fatalf("ReadFile(%s) = %q, want %q", name, got, mf.Data)
+		}
+	}
+}
+
+// TestWriterMultiBlockFileWithFragmentReadAt writes a file spanning several full data blocks plus
+// a short fragment tail, then exercises io.ReaderAt at offsets that land in the first block, a
+// middle block, and the fragment tail, to guard against the block/fragment boundary math in
+// regularFile.ReadAt drifting apart from how Writer lays the same file out.
+func TestWriterMultiBlockFileWithFragmentReadAt(t *testing.T) {
+	const blockSize = 4096
+	const fileSize = blockSize*3 + 100 // three full blocks plus a 100-byte fragment tail
+
+	content := make([]byte, fileSize)
+	for i := range content {
+		content[i] = byte(i)
+	}
+	fsys := fstest.MapFS{"big": &fstest.MapFile{Data: content, Mode: 0644}}
+
+	r := buildImage(t, fsys, WriterOptions{BlockSize: blockSize})
+	f, err := r.Open("big")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+	ra, ok := f.(io.ReaderAt)
+	if !ok {
+		t.Fatalf("regular file does not implement io.ReaderAt")
+	}
+
+	for _, off := range []int64{0, blockSize + 10, blockSize*2 + 1, blockSize * 3, fileSize - 10} {
+		want := content[off:]
+		if len(want) > 32 {
+			want = want[:32]
+		}
+		got := make([]byte, len(want))
+		n, err := ra.ReadAt(got, off)
+		if err != nil && err != io.EOF {
+			t.Fatalf("ReadAt(off=%d): %v", off, err)
+		}
+		if !bytes.Equal(got[:n], want[:n]) {
+			t.Fatalf("ReadAt(off=%d) = %v, want %v", off, got[:n], want[:n])
+		}
+	}
+}
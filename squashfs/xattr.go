@@ -0,0 +1,141 @@
+package squashfs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// xattrHeaderSize is the size of the squashfs_xattr_id_table header that precedes the xattr id
+// index at xattrTableStart.
+const xattrHeaderSize = 16
+
+// xattrDescriptorSize is the on-disk size of a single xattr id descriptor: xattr_ref (uint64),
+// count (uint32) and size (uint32).
+const xattrDescriptorSize = 16
+const xattrDescriptorsPerBlock = metadataSize / xattrDescriptorSize
+
+// xattrValueOOL marks an xattr value as stored out-of-line; those values are not resolved here.
+const xattrValueOOL = 0x100
+const xattrTypeMask = 0xff
+
+// xattrPrefixes maps the low byte of an xattr entry's type field to the on-disk key prefix. These
+// three are the only prefix types squashfs defines; it has no dedicated type for POSIX ACLs (e.g.
+// system.posix_acl_access/default) or any other namespace, so such entries are surfaced through
+// the "unknown.N." fallback in xattrsFor instead.
+var xattrPrefixes = map[uint16]string{
+	0: "user.",
+	1: "trusted.",
+	2: "security.",
+}
+
+// xattrDescriptor locates the xattr key/value pairs attached to one inode.
+type xattrDescriptor struct {
+	ref   uint64
+	count uint32
+	size  uint32
+}
+
+// xattrTable holds everything needed to resolve an inode's xattr index into its key/value pairs.
+type xattrTable struct {
+	kvTableStart uint64
+	descriptors  []xattrDescriptor
+}
+
+// readXattrTable reads the xattr_id_table header at xattrTableStart, followed by the indirect
+// table of xattrDescriptor entries it points to. The key/value metadata stream itself is read
+// lazily, per inode, by xattrsFor.
+func readXattrTable(r io.ReaderAt, xattrTableStart uint64, d Decompressor) (*xattrTable, error) {
+	if xattrTableStart == noTableSentinel {
+		return nil, nil
+	}
+	hdr := make([]byte, xattrHeaderSize)
+	if err := readFullAt(r, hdr, int64(xattrTableStart)); err != nil {
+		return nil, fmt.Errorf("could not read xattr id table header: %v", err)
+	}
+	kvTableStart := binary.LittleEndian.Uint64(hdr[0:8])
+	xattrIDs := binary.LittleEndian.Uint32(hdr[8:12])
+	if xattrIDs == 0 {
+		return &xattrTable{kvTableStart: kvTableStart}, nil
+	}
+
+	numBlocks := int((int(xattrIDs) + xattrDescriptorsPerBlock - 1) / xattrDescriptorsPerBlock)
+	ptrBytes := make([]byte, numBlocks*8)
+	if err := readFullAt(r, ptrBytes, int64(xattrTableStart)+xattrHeaderSize); err != nil {
+		return nil, fmt.Errorf("could not read xattr id index: %v", err)
+	}
+
+	descriptors := make([]xattrDescriptor, 0, xattrIDs)
+	for i := 0; i < numBlocks; i++ {
+		blockStart := binary.LittleEndian.Uint64(ptrBytes[i*8 : i*8+8])
+		_, b, err := readMetadataBlock(r, int64(blockStart), d)
+		if err != nil {
+			return nil, fmt.Errorf("could not read xattr id block %d at %d: %v", i, blockStart, err)
+		}
+		for off := 0; off+xattrDescriptorSize <= len(b) && len(descriptors) < int(xattrIDs); off += xattrDescriptorSize {
+			descriptors = append(descriptors, xattrDescriptor{
+				ref:   binary.LittleEndian.Uint64(b[off : off+8]),
+				count: binary.LittleEndian.Uint32(b[off+8 : off+12]),
+				size:  binary.LittleEndian.Uint32(b[off+12 : off+16]),
+			})
+		}
+	}
+	return &xattrTable{kvTableStart: kvTableStart, descriptors: descriptors}, nil
+}
+
+// xattrsFor reads and decodes the key/value pairs for the given xattr index, as found in an
+// extended inode's xattr field. Out-of-line values are skipped rather than resolved.
+func (r *Reader) xattrsFor(idx uint32) (map[string][]byte, error) {
+	if r.xattrs == nil || idx == noXattr {
+		return nil, nil
+	}
+	if int(idx) >= len(r.xattrs.descriptors) {
+		return nil, fmt.Errorf("xattr index %d out of range of %d descriptors", idx, len(r.xattrs.descriptors))
+	}
+	d := r.xattrs.descriptors[idx]
+	if d.count == 0 {
+		return nil, nil
+	}
+	block, offset := parseInodeRef(d.ref)
+	b, err := readMetadata(r.ra, int64(r.xattrs.kvTableStart), block, offset, int(d.size), r.decompressor)
+	if err != nil {
+		return nil, fmt.Errorf("could not read xattr key/value stream for index %d: %v", idx, err)
+	}
+
+	result := make(map[string][]byte, d.count)
+	pos := 0
+	for i := uint32(0); i < d.count; i++ {
+		if pos+4 > len(b) {
+			return nil, fmt.Errorf("truncated xattr entry %d for index %d", i, idx)
+		}
+		typ := binary.LittleEndian.Uint16(b[pos : pos+2])
+		keySize := int(binary.LittleEndian.Uint16(b[pos+2 : pos+4]))
+		pos += 4
+		if pos+keySize > len(b) {
+			return nil, fmt.Errorf("truncated xattr key for entry %d of index %d", i, idx)
+		}
+		prefix, ok := xattrPrefixes[typ&xattrTypeMask]
+		if !ok {
+			prefix = fmt.Sprintf("unknown.%d.", typ&xattrTypeMask)
+		}
+		key := prefix + string(b[pos:pos+keySize])
+		pos += keySize
+
+		if pos+4 > len(b) {
+			return nil, fmt.Errorf("truncated xattr value header for entry %d of index %d", i, idx)
+		}
+		valSize := int(binary.LittleEndian.Uint32(b[pos : pos+4]))
+		pos += 4
+		if typ&xattrValueOOL != 0 {
+			// the value lives elsewhere in the kv stream; not resolved here.
+			pos += valSize
+			continue
+		}
+		if pos+valSize > len(b) {
+			return nil, fmt.Errorf("truncated xattr value for entry %d of index %d", i, idx)
+		}
+		result[key] = b[pos : pos+valSize]
+		pos += valSize
+	}
+	return result, nil
+}
@@ -0,0 +1,96 @@
+package squashfs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// xattrEntryBytes encodes a single key/value entry in the format xattrsFor expects within the kv
+// stream: type, key size, key, value size and the value bytes (for ool, placeholder bytes that
+// xattrsFor skips rather than reads as a value).
+func xattrEntryBytes(typ uint16, key string, val []byte, ool bool) []byte {
+	b := make([]byte, 4+len(key)+4)
+	binary.LittleEndian.PutUint16(b[0:2], typ)
+	binary.LittleEndian.PutUint16(b[2:4], uint16(len(key)))
+	copy(b[4:], key)
+	binary.LittleEndian.PutUint32(b[4+len(key):4+len(key)+4], uint32(len(val)))
+	if !ool {
+		b = append(b, val...)
+	} else {
+		b = append(b, val...) // placeholder bytes, skipped by xattrsFor rather than read as a value
+	}
+	return b
+}
+
+// TestReadXattrTableRoundTrip hand-crafts an xattr id table plus its kv stream - Writer does not
+// emit one, see the Writer doc comment - and checks that readXattrTable/xattrsFor resolve a known
+// prefix, fall back to "unknown.N." for one squashfs does not define, and skip an out-of-line value
+// without surfacing it.
+func TestReadXattrTableRoundTrip(t *testing.T) {
+	var kvBytes []byte
+	kvBytes = append(kvBytes, xattrEntryBytes(0, "foo", []byte("bar"), false)...)             // user.foo
+	kvBytes = append(kvBytes, xattrEntryBytes(5, "attr", []byte("hi"), false)...)             // unknown.5.attr
+	kvBytes = append(kvBytes, xattrEntryBytes(xattrValueOOL, "big", []byte("xxxx"), true)...) // skipped
+
+	kvBlock := uncompressedMetadataBlock(kvBytes)
+	kvTableStart := 0
+
+	xattrTableStart := len(kvBlock)
+	descriptorBlockOffset := xattrTableStart + xattrHeaderSize + 8
+
+	header := make([]byte, xattrHeaderSize)
+	binary.LittleEndian.PutUint64(header[0:8], uint64(kvTableStart))
+	binary.LittleEndian.PutUint32(header[8:12], 1) // one xattr id (descriptor)
+
+	index := make([]byte, 8)
+	binary.LittleEndian.PutUint64(index, uint64(descriptorBlockOffset))
+
+	descriptor := make([]byte, xattrDescriptorSize)
+	binary.LittleEndian.PutUint64(descriptor[0:8], 0)  // ref: block 0, offset 0 within the kv stream
+	binary.LittleEndian.PutUint32(descriptor[8:12], 3) // count
+	binary.LittleEndian.PutUint32(descriptor[12:16], uint32(len(kvBytes)))
+	descriptorBlock := uncompressedMetadataBlock(descriptor)
+
+	img := append(append(append(append([]byte{}, kvBlock...), header...), index...), descriptorBlock...)
+
+	table, err := readXattrTable(bytes.NewReader(img), uint64(xattrTableStart), &gzipDecompressor{})
+	if err != nil {
+		t.Fatalf("readXattrTable: %v", err)
+	}
+	if len(table.descriptors) != 1 {
+		t.Fatalf("got %d descriptors, want 1", len(table.descriptors))
+	}
+
+	r := &Reader{ra: bytes.NewReader(img), xattrs: table, decompressor: &gzipDecompressor{}}
+	got, err := r.xattrsFor(0)
+	if err != nil {
+		t.Fatalf("xattrsFor: %v", err)
+	}
+
+	want := map[string]string{"user.foo": "bar", "unknown.5.attr": "hi"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d xattrs, want %d: %v", len(got), len(want), got)
+	}
+	for k, v := range want {
+		if string(got[k]) != v {
+			t.Fatalf("xattrsFor()[%q] = %q, want %q", k, got[k], v)
+		}
+	}
+	if _, ok := got["user.big"]; ok {
+		t.Fatalf("xattrsFor() surfaced the out-of-line entry, want it skipped")
+	}
+}
+
+// TestXattrsForNoXattrs verifies that a Reader with no xattr table (the common case, since Writer
+// never emits one) reports no xattrs rather than erroring.
+func TestXattrsForNoXattrs(t *testing.T) {
+	r := &Reader{}
+	got, err := r.xattrsFor(0)
+	if err != nil {
+		t.Fatalf("xattrsFor: %v", err)
+	}
+	if got != nil {
+		t.Fatalf("xattrsFor() = %v, want nil", got)
+	}
+}